@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Disk usage of one type of engine objects, as reported by 'docker system df'
+type DiskUsage struct {
+	// Images, Containers, Local Volumes or Build Cache
+	Type   string
+	Total  int
+	Active int
+	// Size, in bytes
+	Size int
+	// Reclaimable size, in bytes
+	Reclaimable int
+}
+
+// Remove unused objects from the engine, and return the reclaimed space in bytes.
+// The sizes are rounded by the docker CLI, so the result is approximate.
+func (c *CLI) Prune(
+	ctx context.Context,
+	// Remove all images not used by a container
+	// +optional
+	images bool,
+	// Remove all stopped containers
+	// +optional
+	containers bool,
+	// Remove all volumes not used by a container
+	// +optional
+	volumes bool,
+	// Remove the build cache
+	// +optional
+	buildCache bool,
+) (int, error) {
+	var commands [][]string
+	// containers first, so that the images and volumes they used can be removed
+	if containers {
+		commands = append(commands, []string{"docker", "container", "prune", "--force"})
+	}
+	if images {
+		commands = append(commands, []string{"docker", "image", "prune", "--all", "--force"})
+	}
+	if volumes {
+		commands = append(commands, []string{"docker", "volume", "prune", "--all", "--force"})
+	}
+	if buildCache {
+		commands = append(commands, []string{"docker", "builder", "prune", "--all", "--force"})
+	}
+	re := regexp.MustCompile(`(?m)^Total(?: reclaimed space)?:\s*(\S+)`)
+	reclaimed := 0
+	for _, cmd := range commands {
+		out, err := c.uncachedContainer().WithExec(cmd).Stdout(ctx)
+		if err != nil {
+			return reclaimed, err
+		}
+		match := re.FindStringSubmatch(out)
+		if len(match) == 0 {
+			continue
+		}
+		size, err := parseSize(match[1])
+		if err != nil {
+			return reclaimed, err
+		}
+		reclaimed += size
+	}
+	return reclaimed, nil
+}
+
+// Execute 'docker system df'
+func (c *CLI) DiskUsage(ctx context.Context) ([]*DiskUsage, error) {
+	raw, err := c.uncachedContainer().
+		WithExec([]string{"docker", "system", "df", "--format", "json"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(raw, "\n")
+	usage := make([]*DiskUsage, 0, len(lines))
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var info struct {
+			Type        string
+			TotalCount  string
+			Active      string
+			Size        string
+			Reclaimable string // Example: 1.2GB (50%)
+		}
+		if err := json.Unmarshal([]byte(line), &info); err != nil {
+			return usage, err
+		}
+		entry := &DiskUsage{Type: info.Type}
+		if entry.Total, err = strconv.Atoi(info.TotalCount); err != nil {
+			return usage, err
+		}
+		if entry.Active, err = strconv.Atoi(info.Active); err != nil {
+			return usage, err
+		}
+		if entry.Size, err = parseSize(info.Size); err != nil {
+			return usage, err
+		}
+		reclaimable, _, _ := strings.Cut(info.Reclaimable, " ")
+		if entry.Reclaimable, err = parseSize(reclaimable); err != nil {
+			return usage, err
+		}
+		usage = append(usage, entry)
+	}
+	return usage, nil
+}
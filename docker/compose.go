@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+)
+
+// A Docker Compose project, running on the engine
+func (c *CLI) Compose(
+	// The compose project directory
+	project *Directory,
+	// Compose files, relative to the project directory.
+	// By default, compose looks up compose.yaml (or docker-compose.yml)
+	// +optional
+	files []string,
+	// Compose profiles to enable
+	// +optional
+	profiles []string,
+	// The compose project name
+	// +optional
+	// +default="dagger"
+	name string,
+) *Compose {
+	return &Compose{
+		Client:   c,
+		Project:  project,
+		Files:    files,
+		Profiles: profiles,
+		Name:     name,
+	}
+}
+
+// Location of the compose projects in the engine host (and in the CLI container)
+const composeProjectsPath = "/tmp/dagger-compose"
+
+// A Docker Compose project
+type Compose struct {
+	// +private
+	Client *CLI
+	// +private
+	Project  *Directory
+	Files    []string
+	Profiles []string
+	Name     string
+}
+
+// Package the Docker CLI into a container, ready to run 'docker compose' on the project.
+// The project is mounted at the path it's copied to in the engine host by Up.
+func (p *Compose) Container() *Container {
+	return p.Client.
+		uncachedContainer().
+		WithMountedDirectory(p.path(), p.Project).
+		WithWorkdir(p.path())
+}
+
+// The path of the project, in the engine host and in the CLI container
+func (p *Compose) path() string {
+	return composeProjectsPath + "/" + p.Name
+}
+
+// Copy the project into the engine host, replacing a previous copy.
+// The bind mounts with a relative path are resolved by the engine, in its own filesystem.
+func (p *Compose) stage(ctx context.Context) error {
+	helper, err := p.Client.Import(ctx, dag.Container().From("index.docker.io/alpine"))
+	if err != nil {
+		return err
+	}
+	_, err = p.Container().
+		WithExec([]string{"sh", "-c",
+			`tar -C "$0" -cf - . | docker run --rm -i -v "$1:/stage" "$2" sh -c 'rm -rf "/stage/$0" && mkdir -p "/stage/$0" && tar -xf - -C "/stage/$0"' "$3"`,
+			p.path(), composeProjectsPath, helper.target(), p.Name,
+		}).
+		Sync(ctx)
+	return err
+}
+
+func (p *Compose) command(args ...string) []string {
+	cmd := []string{"docker", "compose", "--project-directory", p.path(), "-p", p.Name}
+	for _, file := range p.Files {
+		cmd = append(cmd, "-f", file)
+	}
+	for _, profile := range p.Profiles {
+		cmd = append(cmd, "--profile", profile)
+	}
+	return append(cmd, args...)
+}
+
+// Execute 'docker compose up' (detached).
+// The project is copied into the engine host first, so the relative bind mounts resolve to its files.
+func (p *Compose) Up(
+	ctx context.Context,
+	// The services to start. By default, start all services
+	// +optional
+	services []string,
+	// Wait for the services to be running (and healthy, if they have a healthcheck)
+	// +optional
+	// +default=true
+	wait bool,
+) (*Compose, error) {
+	args := []string{"up", "-d"}
+	if wait {
+		args = append(args, "--wait")
+	}
+	args = append(args, services...)
+	if err := p.stage(ctx); err != nil {
+		return p, err
+	}
+	_, err := p.Container().WithExec(p.command(args...)).Sync(ctx)
+	return p, err
+}
+
+// Execute 'docker compose down'
+func (p *Compose) Down(
+	ctx context.Context,
+	// Remove the named volumes declared in the compose files
+	// +optional
+	volumes bool,
+) (*Compose, error) {
+	args := []string{"down"}
+	if volumes {
+		args = append(args, "--volumes")
+	}
+	_, err := p.Container().WithExec(p.command(args...)).Sync(ctx)
+	return p, err
+}
+
+// Execute 'docker compose ps'
+func (p *Compose) Ps(ctx context.Context) (string, error) {
+	return p.Container().WithExec(p.command("ps", "--all")).Stdout(ctx)
+}
+
+// Execute 'docker compose logs'
+func (p *Compose) Logs(
+	ctx context.Context,
+	// The services to show the logs of. By default, show the logs of all services
+	// +optional
+	services []string,
+) (string, error) {
+	args := append([]string{"logs", "--no-color"}, services...)
+	return p.Container().WithExec(p.command(args...)).Stdout(ctx)
+}
+
+// Execute 'docker compose exec'
+func (p *Compose) Exec(
+	ctx context.Context,
+	// The service to run the command in
+	service string,
+	// The command to run
+	args []string,
+) (string, error) {
+	cmd := append([]string{"exec", "-T", service}, args...)
+	return p.Container().WithExec(p.command(cmd...)).Stdout(ctx)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Start a container in the background, to interact with it later
+func (c *CLI) Start(
+	ctx context.Context,
+	// Name of the image to run.
+	// Example: registry.dagger.io/engine
+	name,
+	// Tag of the image to run.
+	// +optional
+	// +default="latest"
+	tag string,
+	// Additional arguments
+	// +optional
+	args []string,
+	// Environment variables to set. Example: FOO=bar
+	// +optional
+	env []string,
+	// Ports to publish. Example: 8080:80
+	// +optional
+	ports []string,
+	// Directories to copy into volumes, mounted in the container at volumePaths.
+	// The volumes are labeled io.dagger.docker.volume, and removed by Prune with volumes once the container is removed
+	// +optional
+	volumes []*Directory,
+	// Mount paths of the volumes, in the same order
+	// +optional
+	volumePaths []string,
+	// Network to connect the container to
+	// +optional
+	network string,
+	// User to run the container as. Example: 1000:1000
+	// +optional
+	user string,
+	// Override the image entrypoint
+	// +optional
+	entrypoint string,
+) (*DockerContainer, error) {
+	runArgs, volumeNames, err := c.runFlags(ctx, name+":"+tag, runOpts{
+		env:         env,
+		ports:       ports,
+		volumes:     volumes,
+		volumePaths: volumePaths,
+		network:     network,
+		user:        user,
+		entrypoint:  entrypoint,
+		detach:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cmd := append([]string{"run", "--cidfile", "/run-result/cid"}, runArgs...)
+	cmd = append(cmd, name+":"+tag)
+	result, err := c.result(ctx, append(cmd, args...))
+	if err != nil {
+		c.removeVolumes(ctx, volumeNames)
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		// The volumes are kept with the container, when it was created
+		if result.ContainerID == "" {
+			c.removeVolumes(ctx, volumeNames)
+		}
+		return nil, fmt.Errorf("docker run failed with exit code %d: %s", result.ExitCode, result.Stderr)
+	}
+	return &DockerContainer{
+		Client: c,
+		ID:     result.ContainerID,
+	}, nil
+}
+
+// A container running in the Docker Engine
+type DockerContainer struct {
+	// +private
+	Client *CLI
+	ID     string
+}
+
+// Run a docker command and return its stdout
+func (ctr *DockerContainer) docker(ctx context.Context, args ...string) (string, error) {
+	return ctr.Client.
+		uncachedContainer().
+		WithExec(append([]string{"docker"}, args...)).
+		Stdout(ctx)
+}
+
+// Execute 'docker exec' in the container
+func (ctr *DockerContainer) Exec(
+	ctx context.Context,
+	// The command to run
+	args []string,
+	// Environment variables to set. Example: FOO=bar
+	// +optional
+	env []string,
+	// User to run the command as. Example: 1000:1000
+	// +optional
+	user string,
+	// Working directory for the command
+	// +optional
+	workdir string,
+) (*RunResult, error) {
+	cmd := []string{"exec"}
+	for _, val := range env {
+		cmd = append(cmd, "-e", val)
+	}
+	if user != "" {
+		cmd = append(cmd, "--user", user)
+	}
+	if workdir != "" {
+		cmd = append(cmd, "--workdir", workdir)
+	}
+	cmd = append(cmd, ctr.ID)
+	return ctr.Client.result(ctx, append(cmd, args...))
+}
+
+// Return the container logs (stdout and stderr)
+func (ctr *DockerContainer) Logs(ctx context.Context) (string, error) {
+	return ctr.Client.
+		uncachedContainer().
+		WithExec([]string{"sh", "-c", `docker logs "$0" 2>&1`, ctr.ID}).
+		Stdout(ctx)
+}
+
+// Execute 'docker container inspect' and return the raw JSON
+func (ctr *DockerContainer) Inspect(ctx context.Context) (string, error) {
+	return ctr.docker(ctx, "container", "inspect", ctr.ID)
+}
+
+// Execute 'docker stop'
+func (ctr *DockerContainer) Stop(
+	ctx context.Context,
+	// Seconds to wait before killing the container
+	// +optional
+	// +default=10
+	timeout int,
+) (*DockerContainer, error) {
+	_, err := ctr.docker(ctx, "stop", "-t", strconv.Itoa(timeout), ctr.ID)
+	return ctr, err
+}
+
+// Execute 'docker kill'
+func (ctr *DockerContainer) Kill(
+	ctx context.Context,
+	// The signal to send
+	// +optional
+	// +default="KILL"
+	signal string,
+) (*DockerContainer, error) {
+	_, err := ctr.docker(ctx, "kill", "-s", signal, ctr.ID)
+	return ctr, err
+}
+
+// Wait for the container to stop, and return its exit code
+func (ctr *DockerContainer) Wait(ctx context.Context) (int, error) {
+	out, err := ctr.docker(ctx, "wait", ctr.ID)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// Execute 'docker commit' and return the new image
+func (ctr *DockerContainer) Commit(
+	ctx context.Context,
+	// The repository name to apply
+	repository string,
+	// The tag to apply
+	// +optional
+	// +default="latest"
+	tag string,
+) (*Image, error) {
+	out, err := ctr.docker(ctx, "commit", ctr.ID, repository+":"+tag)
+	if err != nil {
+		return nil, err
+	}
+	return &Image{
+		Client:     ctr.Client,
+		LocalID:    strings.TrimSpace(out),
+		Repository: repository,
+		Tag:        tag,
+	}, nil
+}
+
+// Copy a directory out of the container
+func (ctr *DockerContainer) CopyFrom(
+	// The path of the directory in the container
+	path string,
+) *Directory {
+	return ctr.Client.
+		uncachedContainer().
+		WithExec([]string{"sh", "-c", `mkdir -p /copy && docker cp "$0:$1/." /copy`, ctr.ID, path}).
+		Directory("/copy")
+}
+
+// Copy a directory into the container
+func (ctr *DockerContainer) CopyTo(
+	ctx context.Context,
+	// The destination path in the container
+	path string,
+	// The directory to copy
+	dir *Directory,
+) (*DockerContainer, error) {
+	_, err := ctr.Client.
+		uncachedContainer().
+		WithMountedDirectory("/copy", dir).
+		WithExec([]string{"docker", "cp", "/copy/.", ctr.ID + ":" + path}).
+		Sync(ctx)
+	return ctr, err
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// The configuration of an image, as reported by 'docker image inspect'
+type ImageConfig struct {
+	ID           string
+	Architecture string
+	Os           string
+	Variant      string
+	// Creation time, in RFC 3339 format
+	Created string
+	// Size of the image, in bytes
+	Size       int
+	User       string
+	WorkingDir string
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	// Labels, in the form key=value
+	Labels       []string
+	ExposedPorts []string
+	Volumes      []string
+	StopSignal   string
+	Healthcheck  *ImageHealthcheck
+	// Digests of the layers of the root filesystem
+	Layers []string
+}
+
+// The healthcheck of an image
+type ImageHealthcheck struct {
+	Test []string
+	// Durations, in Go duration format. Example: 30s
+	Interval    string
+	Timeout     string
+	StartPeriod string
+	Retries     int
+}
+
+// Execute 'docker image inspect' and return the image configuration
+func (img *Image) Inspect(ctx context.Context) (*ImageConfig, error) {
+	raw, err := img.Client.
+		uncachedContainer().
+		WithExec([]string{"docker", "image", "inspect", img.target()}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var infos []struct {
+		Id           string
+		Architecture string
+		Os           string
+		Variant      string
+		Created      string
+		Size         int
+		Config       struct {
+			User         string
+			WorkingDir   string
+			Entrypoint   []string
+			Cmd          []string
+			Env          []string
+			Labels       map[string]string
+			ExposedPorts map[string]struct{}
+			Volumes      map[string]struct{}
+			StopSignal   string
+			Healthcheck  *struct {
+				Test        []string
+				Interval    time.Duration
+				Timeout     time.Duration
+				StartPeriod time.Duration
+				Retries     int
+			}
+		}
+		RootFS struct {
+			Layers []string
+		}
+	}
+	if err := json.Unmarshal([]byte(raw), &infos); err != nil {
+		return nil, err
+	}
+	if len(infos) != 1 {
+		return nil, fmt.Errorf("unexpected output of docker image inspect: %d images", len(infos))
+	}
+	info := infos[0]
+	config := &ImageConfig{
+		ID:           info.Id,
+		Architecture: info.Architecture,
+		Os:           info.Os,
+		Variant:      info.Variant,
+		Created:      info.Created,
+		Size:         info.Size,
+		User:         info.Config.User,
+		WorkingDir:   info.Config.WorkingDir,
+		Entrypoint:   info.Config.Entrypoint,
+		Cmd:          info.Config.Cmd,
+		Env:          info.Config.Env,
+		ExposedPorts: sortedKeys(info.Config.ExposedPorts),
+		Volumes:      sortedKeys(info.Config.Volumes),
+		StopSignal:   info.Config.StopSignal,
+		Layers:       info.RootFS.Layers,
+	}
+	labels := make([]string, 0, len(info.Config.Labels))
+	for key, val := range info.Config.Labels {
+		labels = append(labels, key+"="+val)
+	}
+	sort.Strings(labels)
+	config.Labels = labels
+	if hc := info.Config.Healthcheck; hc != nil {
+		config.Healthcheck = &ImageHealthcheck{
+			Test:        hc.Test,
+			Interval:    hc.Interval.String(),
+			Timeout:     hc.Timeout.String(),
+			StartPeriod: hc.StartPeriod.String(),
+			Retries:     hc.Retries,
+		}
+	}
+	return config, nil
+}
+
+// The reference the engine knows this image by
+func (img *Image) target() string {
+	if img.LocalID != "" {
+		return img.LocalID
+	}
+	return img.Repository + ":" + img.Tag
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Execute 'docker image rm'
+func (img *Image) Remove(
+	ctx context.Context,
+	// Remove the image even if it is used by stopped containers or has other tags
+	// +optional
+	force bool,
+) error {
+	cmd := []string{"docker", "image", "rm"}
+	if force {
+		cmd = append(cmd, "--force")
+	}
+	_, err := img.Client.
+		uncachedContainer().
+		WithExec(append(cmd, img.target())).
+		Sync(ctx)
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// System information of the engine, as reported by 'docker info'
+type EngineInfo struct {
+	ID                string
+	Name              string
+	ServerVersion     string
+	OperatingSystem   string
+	KernelVersion     string
+	Architecture      string
+	StorageDriver     string
+	CgroupDriver      string
+	CgroupVersion     string
+	Cpus              int
+	MemTotal          int // Total memory, in bytes
+	Containers        int
+	ContainersRunning int
+	ContainersPaused  int
+	ContainersStopped int
+	Images            int
+}
+
+// Execute 'docker info'
+func (c *CLI) Info(ctx context.Context) (*EngineInfo, error) {
+	raw, err := c.uncachedContainer().
+		WithExec([]string{"docker", "info", "--format", "json"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		ID                string
+		Name              string
+		ServerVersion     string
+		OperatingSystem   string
+		KernelVersion     string
+		Architecture      string
+		Driver            string
+		CgroupDriver      string
+		CgroupVersion     string
+		NCPU              int
+		MemTotal          int
+		Containers        int
+		ContainersRunning int
+		ContainersPaused  int
+		ContainersStopped int
+		Images            int
+	}
+	if err := json.Unmarshal([]byte(raw), &info); err != nil {
+		return nil, err
+	}
+	return &EngineInfo{
+		ID:                info.ID,
+		Name:              info.Name,
+		ServerVersion:     info.ServerVersion,
+		OperatingSystem:   info.OperatingSystem,
+		KernelVersion:     info.KernelVersion,
+		Architecture:      info.Architecture,
+		StorageDriver:     info.Driver,
+		CgroupDriver:      info.CgroupDriver,
+		CgroupVersion:     info.CgroupVersion,
+		Cpus:              info.NCPU,
+		MemTotal:          info.MemTotal,
+		Containers:        info.Containers,
+		ContainersRunning: info.ContainersRunning,
+		ContainersPaused:  info.ContainersPaused,
+		ContainersStopped: info.ContainersStopped,
+		Images:            info.Images,
+	}, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A step of the image history
+type ImageHistory struct {
+	Created   string
+	CreatedBy string
+	Comment   string
+	// The step did not produce a filesystem layer. Example: ENV
+	EmptyLayer bool
+	// Digest of the layer produced by the step
+	Digest string
+	// Size of the layer produced by the step, in bytes
+	Size int
+}
+
+// A filesystem layer of an image
+type ImageLayer struct {
+	Digest    string
+	Created   string
+	CreatedBy string
+	// Size of the layer, in bytes
+	Size int
+	// Paths added or modified by the layer
+	Added []string
+	// Paths removed by the layer.
+	// A directory whose previous contents are all removed is listed as "<dir>/*"
+	Removed []string
+	// Files added or modified by the layer
+	Contents *Directory
+}
+
+// An image unpacked from the 'docker image save' archive
+type savedImage struct {
+	dir     *Directory
+	layers  []string
+	diffIDs []string
+	history []struct {
+		Created    string `json:"created"`
+		CreatedBy  string `json:"created_by"`
+		Comment    string `json:"comment"`
+		EmptyLayer bool   `json:"empty_layer"`
+	}
+}
+
+// Return the history of the image, one entry per build step
+func (img *Image) History(ctx context.Context) ([]*ImageHistory, error) {
+	saved, err := img.unpack(ctx)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]*ImageHistory, 0, len(saved.history))
+	layer := 0
+	for _, step := range saved.history {
+		entry := &ImageHistory{
+			Created:    step.Created,
+			CreatedBy:  step.CreatedBy,
+			Comment:    step.Comment,
+			EmptyLayer: step.EmptyLayer,
+		}
+		if !step.EmptyLayer && layer < len(saved.layers) {
+			size, err := saved.dir.File(saved.layers[layer]).Size(ctx)
+			if err != nil {
+				return nil, err
+			}
+			entry.Digest = saved.diffIDs[layer]
+			entry.Size = size
+			layer++
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// Return the filesystem layers of the image, from the base layer up
+func (img *Image) Layers(ctx context.Context) ([]*ImageLayer, error) {
+	saved, err := img.unpack(ctx)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]int, 0, len(saved.layers))
+	for idx, step := range saved.history {
+		if !step.EmptyLayer {
+			steps = append(steps, idx)
+		}
+	}
+	layers := make([]*ImageLayer, 0, len(saved.layers))
+	for idx, layerPath := range saved.layers {
+		archive := saved.dir.File(layerPath)
+		size, err := archive.Size(ctx)
+		if err != nil {
+			return nil, err
+		}
+		listing, err := archiveTools().
+			WithMountedFile("/layer.tar", archive).
+			WithExec([]string{"tar", "-tf", "/layer.tar"}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, err
+		}
+		layer := &ImageLayer{
+			Digest: saved.diffIDs[idx],
+			Size:   size,
+			Contents: archiveTools().
+				WithMountedFile("/layer.tar", archive).
+				WithExec([]string{"sh", "-c", "mkdir /layer && tar -xf /layer.tar -C /layer && find /layer -name '.wh.*' -exec rm -rf {} +"}).
+				Directory("/layer"),
+		}
+		layer.Added, layer.Removed = layerChanges(listing)
+		if idx < len(steps) {
+			layer.Created = saved.history[steps[idx]].Created
+			layer.CreatedBy = saved.history[steps[idx]].CreatedBy
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// Save the image from the engine, and parse its manifest and config
+func (img *Image) unpack(ctx context.Context) (*savedImage, error) {
+	dir := archiveTools().
+		WithMountedFile("/image.tar", img.archive()).
+		WithExec([]string{"sh", "-c", "mkdir /image && tar -xf /image.tar -C /image"}).
+		Directory("/image")
+	raw, err := dir.File("manifest.json").Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var manifests []struct {
+		Config string
+		Layers []string
+	}
+	if err := json.Unmarshal([]byte(raw), &manifests); err != nil {
+		return nil, err
+	}
+	if len(manifests) != 1 {
+		return nil, fmt.Errorf("unexpected image archive: %d manifests", len(manifests))
+	}
+	raw, err = dir.File(manifests[0].Config).Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	saved := &savedImage{
+		dir:    dir,
+		layers: manifests[0].Layers,
+	}
+	var config struct {
+		RootFS struct {
+			DiffIDs []string `json:"diff_ids"`
+		} `json:"rootfs"`
+		History json.RawMessage `json:"history"`
+	}
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return nil, err
+	}
+	if len(config.History) > 0 {
+		if err := json.Unmarshal(config.History, &saved.history); err != nil {
+			return nil, err
+		}
+	}
+	saved.diffIDs = config.RootFS.DiffIDs
+	if len(saved.diffIDs) != len(saved.layers) {
+		return nil, fmt.Errorf("unexpected image archive: %d layers for %d diff IDs", len(saved.layers), len(saved.diffIDs))
+	}
+	return saved, nil
+}
+
+// Split the listing of a layer archive into added and removed paths
+func layerChanges(listing string) (added, removed []string) {
+	for _, line := range strings.Split(listing, "\n") {
+		name := strings.TrimPrefix(strings.TrimSuffix(line, "/"), "./")
+		if name == "" || name == "." {
+			continue
+		}
+		dir, base := path.Split(name)
+		switch {
+		case base == ".wh..wh..opq":
+			removed = append(removed, "/"+dir+"*")
+		case strings.HasPrefix(base, ".wh."):
+			removed = append(removed, "/"+dir+strings.TrimPrefix(base, ".wh."))
+		default:
+			added = append(added, "/"+name)
+		}
+	}
+	return added, removed
+}
+
+// A container with the tools to unpack image archives
+func archiveTools() *Container {
+	return dag.
+		Container().
+		From("index.docker.io/docker:cli").
+		WithoutEntrypoint()
+}
@@ -8,14 +8,36 @@ import (
 	"fmt"
 	"math/big"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
 	dockerHostname = "dockerd"
 	dockerEndpoint = fmt.Sprintf("tcp://%s:2375", dockerHostname)
+	buildxBuilder  = "dagger-buildx"
+	binfmtImage    = "index.docker.io/tonistiigi/binfmt:latest"
+	// Label of the volumes created from directories
+	volumeLabel = "io.dagger.docker.volume"
 )
 
+// Location of the docker CLI binary, replaced by the wrapper
+const dockerBinary = "/usr/local/libexec/docker-cli"
+
+// Wrapper around the docker CLI, waiting for the engine to answer /_ping before running the command.
+// The engine service is up as soon as its port is open, which can be before the API is ready.
+const readyWrapper = `#!/bin/sh
+for i in $(seq 1 ${DOCKER_READY_TIMEOUT:-60}); do
+	if [ "$(wget -q -T 1 -O - http://${DOCKER_HOST#tcp://}/_ping 2>/dev/null)" = "OK" ]; then
+		exec ` + dockerBinary + ` "$@"
+	fi
+	sleep 1
+done
+echo "docker engine at $DOCKER_HOST is not ready" >&2
+exit 1
+`
+
 // A Dagger module to integrate with Docker
 type Docker struct {
 }
@@ -34,13 +56,17 @@ func (e *Docker) Engine(
 	// Use in combination with `persist`
 	// +optional
 	namespace string,
+	// Start the engine with an empty state, and don't persist it.
+	// Takes precedence over `persist`
+	// +optional
+	ephemeral bool,
 ) *Service {
 	ctr := dag.
 		Container().
 		From(fmt.Sprintf("index.docker.io/docker:%s-dind", version)).
 		WithoutEntrypoint().
 		WithExposedPort(2375)
-	if persist {
+	if persist && !ephemeral {
 		volumeName := "docker-engine-state-"+version
 		if namespace != "" {
 			volumeName = volumeName + "-" + namespace
@@ -74,7 +100,7 @@ func (d *Docker) CLI(
 	engine *Service,
 ) *CLI {
 	if engine == nil {
-		engine = d.Engine(version, true, "")
+		engine = d.Engine(version, true, "", false)
 	}
 	return &CLI{
 		Engine: engine,
@@ -88,12 +114,36 @@ type CLI struct {
 
 // Package the Docker CLI into a container, wired to an engine
 func (c *CLI) Container() *Container {
-	return dag.
+	ctr := dag.
 		Container().
-		From(fmt.Sprintf("index.docker.io/docker:cli")).
+		From(fmt.Sprintf("index.docker.io/docker:cli"))
+	// The wrapper replaces the binary, so it's used whatever the PATH
+	return ctr.
 		WithoutEntrypoint().
 		WithServiceBinding("dockerd", c.Engine).
-		WithEnvVariable("DOCKER_HOST", dockerEndpoint)
+		WithEnvVariable("DOCKER_HOST", dockerEndpoint).
+		WithFile(dockerBinary, ctr.File("/usr/local/bin/docker")).
+		WithNewFile("/usr/local/bin/docker", ContainerWithNewFileOpts{
+			Contents:    readyWrapper,
+			Permissions: 0755,
+		})
+}
+
+// Wait for the engine to answer /_ping.
+// The containers bound to the engine service directly (not through the CLI) don't wait for it:
+// start the service (Service.Start) and wait for it before running them.
+func (c *CLI) WaitReady(ctx context.Context) error {
+	_, err := c.uncachedContainer().
+		WithExec([]string{"docker", "version"}).
+		Sync(ctx)
+	return err
+}
+
+// Package the Docker CLI into a container, for commands acting on the live state of the engine.
+// The commands are never cached.
+func (c *CLI) uncachedContainer() *Container {
+	return c.Container().
+		WithEnvVariable("CACHEBUSTER", time.Now().String())
 }
 
 // Execute 'docker pull'
@@ -163,7 +213,8 @@ func (c *CLI) Import(
 	// The container to load
 	container *Container,
 ) (*Image, error) {
-	stdout, err := c.Container().
+	// The image may have been removed from the engine since a previous import: it's loaded again
+	stdout, err := c.uncachedContainer().
 		WithMountedFile("import.tar", container.AsTarball()).
 		WithExec([]string{
 			"docker",
@@ -201,6 +252,258 @@ func randomName(length int) (string, error) {
 	return string(b), nil
 }
 
+// Execute 'docker build' on a directory
+func (c *CLI) Build(
+	ctx context.Context,
+	// The build context
+	context *Directory,
+	// Path to the Dockerfile, relative to the build context
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Build-time variables. Example: VERSION=1.0
+	// +optional
+	buildArgs []string,
+	// The build stage to build
+	// +optional
+	target string,
+	// The names to apply to the image. Example: registry.dagger.io/engine:latest
+	// +optional
+	tags []string,
+	// The platform to build for. Example: linux/amd64
+	// +optional
+	platform string,
+) (*Image, error) {
+	cmd := []string{"docker", "build"}
+	cmd = append(cmd, buildFlags(dockerfile, buildArgs, target, tags, platform)...)
+	// The image is built in the engine: a cached build would return an image it may not have
+	iid, err := c.uncachedContainer().
+		WithMountedDirectory("/build/context", context).
+		WithExec(cmd).
+		File("/build/iid").
+		Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	img := &Image{
+		Client:  c,
+		LocalID: strings.TrimSpace(iid),
+	}
+	if len(tags) > 0 {
+		img.Repository, img.Tag = splitRef(tags[0])
+	}
+	return img, nil
+}
+
+// Execute 'docker buildx build' with a BuildKit builder running in the engine.
+// Each platform is built and loaded into the engine separately: one image is returned per platform.
+// The platforms other than the native one are built with QEMU emulation, installed in the kernel of the host
+// running the engine (it requires a privileged engine, which is the default).
+func (c *CLI) Buildx(
+	ctx context.Context,
+	// The build context
+	context *Directory,
+	// Path to the Dockerfile, relative to the build context
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Build-time variables. Example: VERSION=1.0
+	// +optional
+	buildArgs []string,
+	// The build stage to build
+	// +optional
+	target string,
+	// The names to apply to the images. Example: registry.dagger.io/engine:latest
+	// With more than one platform, the platform is appended to the tag. Example: registry.dagger.io/engine:latest-linux-arm64
+	// +optional
+	tags []string,
+	// The platforms to build for. Example: linux/amd64
+	// +optional
+	platforms []string,
+	// A cache volume to import the build cache from, and export it to
+	// +optional
+	cache *CacheVolume,
+	// A directory to import the build cache from (exported by buildx with type=local, see BuildxCache)
+	// +optional
+	cacheFrom *Directory,
+	// Secrets to expose to the build, with the IDs in secretIds.
+	// Example: RUN --mount=type=secret,id=token
+	// +optional
+	secrets []*Secret,
+	// IDs of the secrets, in the same order
+	// +optional
+	secretIds []string,
+) ([]*Image, error) {
+	ctr, flags, err := c.buildxContainer(ctx, context, platforms, cacheFrom, secrets, secretIds)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		opts := ContainerWithMountedCacheOpts{Sharing: Locked}
+		ctr = ctr.WithMountedCache("/build/cache", cache, opts)
+		flags = append(flags,
+			"--cache-from", "type=local,src=/build/cache",
+			"--cache-to", "type=local,dest=/build/cache,mode=max")
+	}
+	if len(platforms) == 0 {
+		platforms = []string{""}
+	}
+	images := make([]*Image, 0, len(platforms))
+	for _, platform := range platforms {
+		platformTags := tags
+		if len(platforms) > 1 {
+			platformTags = make([]string, 0, len(tags))
+			for _, tag := range tags {
+				repository, version := splitRef(tag)
+				platformTags = append(platformTags, repository+":"+version+"-"+strings.ReplaceAll(platform, "/", "-"))
+			}
+		}
+		cmd := append(buildxCommand(), "--load")
+		cmd = append(cmd, flags...)
+		cmd = append(cmd, buildFlags(dockerfile, buildArgs, target, platformTags, platform)...)
+		iid, err := ctr.
+			WithExec(cmd).
+			File("/build/iid").
+			Contents(ctx)
+		if err != nil {
+			return images, err
+		}
+		img := &Image{
+			Client:  c,
+			LocalID: strings.TrimSpace(iid),
+		}
+		if len(platformTags) > 0 {
+			img.Repository, img.Tag = splitRef(platformTags[0])
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// Execute 'docker buildx build' for all the platforms at once, and return the build cache
+// (exported by buildx with type=local, to import with cacheFrom). No image is loaded into the engine.
+func (c *CLI) BuildxCache(
+	ctx context.Context,
+	// The build context
+	context *Directory,
+	// Path to the Dockerfile, relative to the build context
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Build-time variables. Example: VERSION=1.0
+	// +optional
+	buildArgs []string,
+	// The build stage to build
+	// +optional
+	target string,
+	// The platforms to build for. Example: linux/amd64
+	// +optional
+	platforms []string,
+	// A directory to import the build cache from (a previous export)
+	// +optional
+	cacheFrom *Directory,
+	// Secrets to expose to the build, with the IDs in secretIds.
+	// Example: RUN --mount=type=secret,id=token
+	// +optional
+	secrets []*Secret,
+	// IDs of the secrets, in the same order
+	// +optional
+	secretIds []string,
+) (*Directory, error) {
+	ctr, flags, err := c.buildxContainer(ctx, context, platforms, cacheFrom, secrets, secretIds)
+	if err != nil {
+		return nil, err
+	}
+	cmd := append(buildxCommand(), "--output", "type=cacheonly", "--cache-to", "type=local,dest=/build/cache-to,mode=max")
+	cmd = append(cmd, flags...)
+	cmd = append(cmd, buildFlags(dockerfile, buildArgs, target, nil, strings.Join(platforms, ","))...)
+	return ctr.
+		WithExec(cmd).
+		Directory("/build/cache-to").
+		Sync(ctx)
+}
+
+// Package the Docker CLI into a container ready to run 'docker buildx build', and return the flags
+// for the secrets and the imported cache. The QEMU emulators are registered when platforms are set.
+func (c *CLI) buildxContainer(
+	ctx context.Context,
+	context *Directory,
+	platforms []string,
+	cacheFrom *Directory,
+	secrets []*Secret,
+	secretIds []string,
+) (*Container, []string, error) {
+	if len(secrets) != len(secretIds) {
+		return nil, nil, fmt.Errorf("each secret needs exactly one ID: %d secrets, %d IDs", len(secrets), len(secretIds))
+	}
+	if len(platforms) > 0 {
+		// Register the QEMU emulators (the ones already registered are kept)
+		_, err := c.uncachedContainer().
+			WithExec([]string{"docker", "run", "--privileged", "--rm", binfmtImage, "--install", "all"}).
+			Sync(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	// The builds use the builder in the engine (and load images into it): they are never cached
+	ctr := c.uncachedContainer().
+		WithMountedDirectory("/build/context", context)
+	var flags []string
+	for i, secret := range secrets {
+		path := "/run/secrets/" + secretIds[i]
+		ctr = ctr.WithMountedSecret(path, secret)
+		flags = append(flags, "--secret", "id="+secretIds[i]+",src="+path)
+	}
+	if cacheFrom != nil {
+		ctr = ctr.WithMountedDirectory("/build/cache-from", cacheFrom)
+		flags = append(flags, "--cache-from", "type=local,src=/build/cache-from")
+	}
+	return ctr, flags, nil
+}
+
+// The 'docker buildx build' command, run with the builder
+func buildxCommand() []string {
+	return []string{
+		"sh", "-c",
+		// The builder only lives in the client config, so it's (re)created for each build.
+		// Its BuildKit container is kept in the engine and reused.
+		`docker buildx create --name "$0" --driver docker-container --use >/dev/null && exec "$@"`,
+		buildxBuilder,
+		"docker", "buildx", "build",
+	}
+}
+
+// Flags shared by 'docker build' and 'docker buildx build', including the build context
+func buildFlags(dockerfile string, buildArgs []string, target string, tags []string, platform string) []string {
+	flags := []string{
+		"-f", "/build/context/" + dockerfile,
+		"--iidfile", "/build/iid",
+	}
+	for _, arg := range buildArgs {
+		flags = append(flags, "--build-arg", arg)
+	}
+	if target != "" {
+		flags = append(flags, "--target", target)
+	}
+	for _, tag := range tags {
+		flags = append(flags, "-t", tag)
+	}
+	if platform != "" {
+		flags = append(flags, "--platform", platform)
+	}
+	return append(flags, "/build/context")
+}
+
+// Split an image name into its repository and tag
+func splitRef(ref string) (string, string) {
+	// A colon before the last slash is a registry port, not a tag
+	i := strings.LastIndex(ref, ":")
+	if i < 0 || i < strings.LastIndex(ref, "/") {
+		return ref, "latest"
+	}
+	return ref[:i], ref[i+1:]
+}
+
 // Look up an image in the local Docker Engine cache
 // If exactly one image matches the filters, return it.
 // Otherwise, return an error.
@@ -217,7 +520,7 @@ func (c *CLI) Image(
 	// +optional
 	localID string,
 ) (*Image, error) {
-	images, err := c.Images(ctx, repository, tag, localID)
+	images, err := c.Images(ctx, repository, tag, localID, nil, false, "", "", "", "")
 	if err != nil {
 		return nil, err
 	}
@@ -243,12 +546,202 @@ func (c *CLI) Run(
 	// Additional arguments
 	// +optional
 	args []string,
-) (string, error) {
-	cmd := []string{"docker", "run", name + ":" + tag}
-	if args != nil {
-		cmd = append(cmd, args...)
+	// Environment variables to set. Example: FOO=bar
+	// +optional
+	env []string,
+	// Ports to publish. Example: 8080:80
+	// +optional
+	ports []string,
+	// Directories to copy into volumes, mounted in the container at volumePaths.
+	// The volumes are removed after the run, unless the container is detached or kept:
+	// they are labeled io.dagger.docker.volume, and removed by Prune with volumes
+	// +optional
+	volumes []*Directory,
+	// Mount paths of the volumes, in the same order
+	// +optional
+	volumePaths []string,
+	// Network to connect the container to
+	// +optional
+	network string,
+	// User to run the container as. Example: 1000:1000
+	// +optional
+	user string,
+	// Override the image entrypoint
+	// +optional
+	entrypoint string,
+	// Run the container in the background. Stdout is the container ID
+	// +optional
+	detach bool,
+	// Remove the container when it exits
+	// +optional
+	remove bool,
+) (*RunResult, error) {
+	runArgs, volumeNames, err := c.runFlags(ctx, name+":"+tag, runOpts{
+		env:         env,
+		ports:       ports,
+		volumes:     volumes,
+		volumePaths: volumePaths,
+		network:     network,
+		user:        user,
+		entrypoint:  entrypoint,
+		detach:      detach,
+		remove:      remove,
+	})
+	if err != nil {
+		return nil, err
 	}
-	return c.Container().WithExec(cmd).Stdout(ctx)
+	cmd := append([]string{"run", "--cidfile", "/run-result/cid"}, runArgs...)
+	cmd = append(cmd, name+":"+tag)
+	result, err := c.result(ctx, append(cmd, args...))
+	if err != nil {
+		c.removeVolumes(ctx, volumeNames)
+		return nil, err
+	}
+	// The volumes are not used anymore when the container is gone, or when it couldn't be created
+	if len(volumeNames) > 0 && ((!detach && remove) || result.ContainerID == "") {
+		_, err = c.uncachedContainer().
+			WithExec(append([]string{"docker", "volume", "rm"}, volumeNames...)).
+			Sync(ctx)
+	}
+	return result, err
+}
+
+// The result of a command run with the docker CLI
+type RunResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// The ID of the container, when the command created one
+	ContainerID string
+}
+
+// Options for 'docker run' and 'docker create'
+type runOpts struct {
+	env         []string
+	ports       []string
+	volumes     []*Directory
+	volumePaths []string
+	network     string
+	user        string
+	entrypoint  string
+	detach      bool
+	remove      bool
+}
+
+// Build the 'docker run' (or 'docker create') flags.
+// The directories are copied into new volumes first: their names are returned along with the flags.
+func (c *CLI) runFlags(ctx context.Context, image string, opts runOpts) ([]string, []string, error) {
+	if len(opts.volumes) != len(opts.volumePaths) {
+		return nil, nil, fmt.Errorf("each volume needs exactly one mount path: %d volumes, %d paths", len(opts.volumes), len(opts.volumePaths))
+	}
+	var flags, volumes []string
+	for _, val := range opts.env {
+		flags = append(flags, "-e", val)
+	}
+	for _, val := range opts.ports {
+		flags = append(flags, "-p", val)
+	}
+	for i, dir := range opts.volumes {
+		volume, err := c.volumeFrom(ctx, image, dir)
+		if err != nil {
+			c.removeVolumes(ctx, volumes)
+			return nil, nil, err
+		}
+		volumes = append(volumes, volume)
+		flags = append(flags, "-v", volume+":"+opts.volumePaths[i])
+	}
+	if opts.network != "" {
+		flags = append(flags, "--network", opts.network)
+	}
+	if opts.user != "" {
+		flags = append(flags, "--user", opts.user)
+	}
+	if opts.entrypoint != "" {
+		flags = append(flags, "--entrypoint", opts.entrypoint)
+	}
+	if opts.detach {
+		flags = append(flags, "-d")
+	}
+	if opts.remove {
+		flags = append(flags, "--rm")
+	}
+	return flags, volumes, nil
+}
+
+// Create a new (labeled) volume with the contents of a directory.
+// The files are copied with a throwaway container, created (but not started) from the image.
+// The container is always removed, and so is the volume when the copy fails.
+func (c *CLI) volumeFrom(ctx context.Context, image string, dir *Directory) (string, error) {
+	suffix, err := randomName(12)
+	if err != nil {
+		return "", err
+	}
+	volume := "dagger-volume-" + suffix
+	_, err = c.uncachedContainer().
+		WithMountedDirectory("/volume", dir).
+		WithExec([]string{"sh", "-c", `
+			docker volume create --label "$2" "$0" >/dev/null &&
+			docker create --name "$0" --entrypoint "" -v "$0:/volume" "$1" true >/dev/null &&
+			docker cp /volume/. "$0:/volume"
+			status=$?
+			docker rm -f "$0" >/dev/null 2>&1
+			[ $status -eq 0 ] || docker volume rm "$0" >/dev/null 2>&1
+			exit $status`,
+			volume, image, volumeLabel,
+		}).
+		Sync(ctx)
+	if err != nil {
+		return "", err
+	}
+	return volume, nil
+}
+
+// Remove the volumes created for a run that failed.
+// It's best effort: the error of the run is the one reported.
+func (c *CLI) removeVolumes(ctx context.Context, volumes []string) {
+	if len(volumes) == 0 {
+		return
+	}
+	_, _ = c.uncachedContainer().
+		WithExec(append([]string{"sh", "-c", `docker volume rm "$@" >/dev/null 2>&1; true`, "sh"}, volumes...)).
+		Sync(ctx)
+}
+
+// Run a docker command, capturing its output and exit code instead of failing
+func (c *CLI) result(ctx context.Context, args []string) (*RunResult, error) {
+	script := `
+		mkdir -p /run-result
+		docker "$@" > /run-result/stdout 2> /run-result/stderr
+		echo $? > /run-result/exit-code`
+	out := c.uncachedContainer().
+		WithExec(append([]string{"sh", "-c", script, "docker"}, args...)).
+		Directory("/run-result")
+	return readResult(ctx, out)
+}
+
+func readResult(ctx context.Context, out *Directory) (*RunResult, error) {
+	entries, err := out.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		contents, err := out.File(entry).Contents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		files[entry] = contents
+	}
+	exitCode, err := strconv.Atoi(strings.TrimSpace(files["exit-code"]))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected exit code: %q", files["exit-code"])
+	}
+	return &RunResult{
+		Stdout:      files["stdout"],
+		Stderr:      files["stderr"],
+		ExitCode:    exitCode,
+		ContainerID: strings.TrimSpace(files["cid"]),
+	}, nil
 }
 
 // List images on the local Docker Engine cache
@@ -263,13 +756,48 @@ func (c *CLI) Images(
 	// Filter by image ID
 	// +optional
 	localID string,
+	// Filter by label. Example: org.opencontainers.image.vendor=Dagger
+	// +optional
+	labels []string,
+	// Only list dangling (untagged) images
+	// +optional
+	dangling bool,
+	// Only list images created before this image
+	// +optional
+	before string,
+	// Only list images created since this image
+	// +optional
+	since string,
+	// Filter by reference glob. Example: registry.dagger.io/*:v0.10.*
+	// +optional
+	reference string,
+	// Filter by image digest
+	// +optional
+	digest string,
 ) ([]*Image, error) {
-	raw, err := c.Container().
-		WithExec([]string{
-			"docker", "image", "list",
-			"--no-trunc",
-			"--format", "json", // "{{json .}}",
-		}).
+	cmd := []string{
+		"docker", "image", "list",
+		"--no-trunc",
+		"--digests",
+		"--format", "json", // "{{json .}}",
+	}
+	for _, label := range labels {
+		cmd = append(cmd, "--filter", "label="+label)
+	}
+	if dangling {
+		cmd = append(cmd, "--filter", "dangling=true")
+	}
+	if before != "" {
+		cmd = append(cmd, "--filter", "before="+before)
+	}
+	if since != "" {
+		cmd = append(cmd, "--filter", "since="+since)
+	}
+	if reference != "" {
+		cmd = append(cmd, "--filter", "reference="+reference)
+	}
+	raw, err := c.uncachedContainer().
+		WithExec(cmd).
 		Stdout(ctx)
 	if err != nil {
 		return nil, err
@@ -284,6 +812,9 @@ func (c *CLI) Images(
 			ID         string
 			Repository string
 			Tag        string
+			Digest     string
+			CreatedAt  string
+			Size       string
 		}
 		if err := json.Unmarshal([]byte(line), &imageInfo); err != nil {
 			return images, err
@@ -295,19 +826,59 @@ func (c *CLI) Images(
 			continue
 		}
 		if localID != "" && (!strings.HasPrefix(imageInfo.ID, localID)) {
-			fmt.Printf("======> %q != %q", imageInfo.ID, localID)
 			continue
 		}
+		if digest != "" && (digest != imageInfo.Digest) {
+			continue
+		}
+		size, err := parseSize(imageInfo.Size)
+		if err != nil {
+			return images, err
+		}
 		images = append(images, &Image{
 			Client:     c,
 			LocalID:    imageInfo.ID,
 			Repository: imageInfo.Repository,
 			Tag:        imageInfo.Tag,
+			Digest:     imageInfo.Digest,
+			CreatedAt:  imageInfo.CreatedAt,
+			Size:       size,
 		})
 	}
 	return images, nil
 }
 
+// Parse a size printed by the docker CLI. Example: 12.3MB
+// The CLI rounds the sizes, so the result is approximate.
+func parseSize(size string) (int, error) {
+	size = strings.TrimSpace(size)
+	idx := strings.IndexFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if idx < 0 {
+		idx = len(size)
+	}
+	value, err := strconv.ParseFloat(size[:idx], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+	units := map[string]float64{
+		"":   1,
+		"B":  1,
+		"kB": 1e3,
+		"KB": 1e3,
+		"MB": 1e6,
+		"GB": 1e9,
+		"TB": 1e12,
+		"PB": 1e15,
+	}
+	unit, ok := units[strings.TrimSpace(size[idx:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit", size)
+	}
+	return int(value * unit), nil
+}
+
 // An image store in the local Docker Engine cache
 type Image struct {
 	// +private
@@ -315,19 +886,26 @@ type Image struct {
 	LocalID    string // The local identifer of the docker image. Can't call it ID...
 	Tag        string
 	Repository string
+	Digest     string // The repository digest, if the image was pulled or pushed
+	CreatedAt  string
+	Size       int // Approximate size, in bytes
 }
 
 // Export this image from the docker engine into Dagger
 func (img *Image) Export() *Container {
-	archive := img.Client.
+	return dag.Container().Import(img.archive())
+}
+
+// Execute 'docker image save' and return the archive
+func (img *Image) archive() *File {
+	return img.Client.
 		Container().
 		WithExec([]string{
-			"image", "save",
-			"-o", "export.tar",
-			img.LocalID,
+			"docker", "image", "save",
+			"-o", "/export.tar",
+			img.target(),
 		}).
-		File("export.tar")
-	return dag.Container().Import(archive)
+		File("/export.tar")
 }
 
 // Duplicate this image under a new name.
@@ -343,7 +921,7 @@ func (img *Image) Duplicate(
 		return nil, fmt.Errorf("Can't tag image: local ID not set")
 	}
 	_, err := img.Client.
-		Container().
+		uncachedContainer().
 		WithExec([]string{"docker", "tag", img.LocalID, repository + ":" + tag}).
 		Sync(ctx)
 	if err != nil {
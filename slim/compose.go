@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+)
+
+// Slim the image of a service in a docker-compose project.
+// The compose project is started in the ephemeral dockerd, so the service runs with its dependencies.
+// The project directory is copied into the dockerd host filesystem, so the relative bind mounts in the compose file work.
+func (s *Slim) SlimCompose(
+	ctx context.Context,
+	// Compose project directory
+	project *Directory,
+	// Compose file (relative to the project directory)
+	composeFile string,
+	// Compose service to slim
+	service string,
+	// Execution mode to use
+	// +optional
+	// +default="docker"
+	mode string,
+	// Enable running HTTP probes against the temporary container (test to false to disable)
+	// +optional
+	// +default=true
+	probeHttp bool,
+	// Probe HTTP - exit when all HTTP probe commands fail
+	// +optional
+	// +default=true
+	probeHttpExitOnFailure bool,
+	// Probe HTTP - comma separated subset of ports to probe
+	// +optional
+	probeHttpPorts string,
+	// Map all exposed ports to the same host ports analyzing image at runtime
+	// +optional
+	// +default=true
+	publishExposedPorts bool,
+	// Select when to start processing the collected telemetry - enter | signal | probe | exec | timeout-number-in-seconds | container.probe (can combine probe and exec like this: probe&exe)
+	// +optional
+	continueAfter string,
+	// Show container logs from the container used to perform dynamic inspection
+	// +optional
+	// +default=false
+	showClogs bool,
+	// Show debugging information
+	// +optional
+	// +default=false
+	slimDebug bool,
+) (*Container, error) {
+	run, err := s.run(ctx,
+		&slimTarget{
+			project:     project,
+			composeFile: composeFile,
+			service:     service,
+		},
+		mode,
+		probeHttp,
+		probeHttpExitOnFailure,
+		probeHttpPorts,
+		publishExposedPorts,
+		continueAfter,
+		showClogs,
+		slimDebug)
+	if err != nil {
+		return nil, err
+	}
+
+	defer run.cleanup(ctx)
+
+	if run.exitCode != 0 {
+		return nil, classifyFailure(run.exitCode, run.output)
+	}
+
+	return s.export(ctx, run)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	restoreContextPath = "/slim-restore"
+)
+
+// imageConfig is the docker image config (as reported by 'docker image inspect')
+type imageConfig struct {
+	User         string
+	ExposedPorts map[string]struct{}
+	Env          []string
+	Cmd          []string
+	Healthcheck  *struct {
+		Test        []string
+		Interval    time.Duration
+		Timeout     time.Duration
+		StartPeriod time.Duration
+		Retries     int
+	}
+	Volumes    map[string]struct{}
+	WorkingDir string
+	Entrypoint []string
+	Labels     map[string]string
+	StopSignal string
+}
+
+// restoreConfig compares the config of the original image with the slimmed one
+// and rebuilds the slimmed image (in the dockerd) with anything mint or the export dropped.
+// The config explicitly removed with the slim options is not restored.
+// Annotations are not part of the image config kept by docker: see missingAnnotations.
+func (s *Slim) restoreConfig(ctx context.Context, run *slimRun) ([]string, error) {
+	if run.imageID == "" {
+		//nothing to compare with (compose targets)
+		return nil, nil
+	}
+
+	original, err := inspectConfig(ctx, run.docker, run.id, run.imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	slimmed, err := inspectConfig(ctx, run.docker, run.id, run.outputRef())
+	if err != nil {
+		return nil, err
+	}
+
+	var restored, instructions []string
+	restore := func(item, instruction string) {
+		restored = append(restored, item)
+		instructions = append(instructions, instruction)
+	}
+
+	if len(original.Entrypoint) > 0 && len(slimmed.Entrypoint) == 0 {
+		restore("entrypoint", "ENTRYPOINT "+toJSONList(original.Entrypoint))
+
+		//setting the entrypoint resets the cmd
+		if len(slimmed.Cmd) > 0 {
+			instructions = append(instructions, "CMD "+toJSONList(slimmed.Cmd))
+		}
+	}
+
+	if len(original.Cmd) > 0 && len(slimmed.Cmd) == 0 {
+		restore("cmd", "CMD "+toJSONList(original.Cmd))
+	}
+
+	slimmedEnv := map[string]bool{}
+	for _, val := range slimmed.Env {
+		name, _, _ := strings.Cut(val, "=")
+		slimmedEnv[name] = true
+	}
+
+	for _, val := range original.Env {
+		name, value, _ := strings.Cut(val, "=")
+		if slimmedEnv[name] || contains(s.removeEnvVars, name) {
+			continue
+		}
+
+		restore("env:"+name, fmt.Sprintf("ENV %s=%s", name, quote(value)))
+	}
+
+	for _, name := range sortedKeys(original.Labels) {
+		if _, ok := slimmed.Labels[name]; ok || contains(s.removeLabels, name) {
+			continue
+		}
+
+		restore("label:"+name, fmt.Sprintf("LABEL %s=%s", quote(name), quote(original.Labels[name])))
+	}
+
+	for _, port := range sortedKeys(original.ExposedPorts) {
+		if _, ok := slimmed.ExposedPorts[port]; ok || contains(s.removeExposePorts, port) {
+			continue
+		}
+
+		//the default protocol can be omitted when the port is removed
+		if strings.HasSuffix(port, "/tcp") && contains(s.removeExposePorts, strings.TrimSuffix(port, "/tcp")) {
+			continue
+		}
+
+		restore("expose:"+port, "EXPOSE "+port)
+	}
+
+	for _, volume := range sortedKeys(original.Volumes) {
+		if _, ok := slimmed.Volumes[volume]; ok {
+			continue
+		}
+
+		restore("volume:"+volume, "VOLUME "+toJSONList([]string{volume}))
+	}
+
+	if original.User != "" && slimmed.User == "" {
+		restore("user", "USER "+original.User)
+	}
+
+	if original.WorkingDir != "" && slimmed.WorkingDir == "" {
+		restore("workdir", "WORKDIR "+original.WorkingDir)
+	}
+
+	if original.StopSignal != "" && slimmed.StopSignal == "" {
+		restore("stop-signal", "STOPSIGNAL "+original.StopSignal)
+	}
+
+	if original.Healthcheck != nil && slimmed.Healthcheck == nil {
+		restore("healthcheck", healthcheckInstruction(original))
+	}
+
+	if len(instructions) == 0 {
+		return nil, nil
+	}
+
+	dockerfile := fmt.Sprintf("FROM %s\n%s\n", run.outputRef(), strings.Join(instructions, "\n"))
+	_, err = run.docker.
+		Container().
+		WithNewFile(restoreContextPath+"/Dockerfile", ContainerWithNewFileOpts{
+			Contents: dockerfile,
+		}).
+		//the classic builder uses the local images as is
+		WithEnvVariable("DOCKER_BUILDKIT", "0").
+		//the output reference can be the same for each run (WithOutputImage), but not the image
+		WithEnvVariable("SLIM_RUN_ID", run.id).
+		WithExec([]string{"docker", "build", "-q", "-t", run.outputRef(), restoreContextPath}).
+		Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func inspectConfig(ctx context.Context, docker *DockerCli, runID string, ref string) (*imageConfig, error) {
+	out, err := docker.
+		Container().
+		WithEnvVariable("SLIM_RUN_ID", runID).
+		WithExec([]string{"docker", "image", "inspect", "--format", "{{json .Config}}", ref}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var config imageConfig
+	if err := json.Unmarshal([]byte(out), &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// missingAnnotations returns the manifest annotations of the original container the slimmed one doesn't have
+// (docker drops them, and they can't be set on the container), as reported in the not restored config
+func missingAnnotations(ctx context.Context, original *Container, slimmed *Container) ([]string, error) {
+	want, err := annotations(ctx, original)
+	if err != nil || len(want) == 0 {
+		return nil, err
+	}
+
+	have, err := annotations(ctx, slimmed)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, key := range sortedKeys(want) {
+		if _, ok := have[key]; !ok {
+			missing = append(missing, "annotation:"+key)
+		}
+	}
+
+	return missing, nil
+}
+
+// annotations reads the manifest annotations from the OCI layout of the container
+func annotations(ctx context.Context, container *Container) (map[string]string, error) {
+	layout := dag.
+		Container().
+		From(toolsImage).
+		WithMountedFile("/image.tar", container.AsTarball()).
+		WithExec([]string{"sh", "-c", "mkdir /image && tar -xf /image.tar -C /image"}).
+		Directory("/image")
+
+	raw, err := layout.File("index.json").Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	//the index of the layout (and of a multi-platform manifest list) points to the image manifest
+	for {
+		var manifest struct {
+			Manifests []struct {
+				Digest string
+			}
+			Annotations map[string]string
+		}
+		if err := json.Unmarshal([]byte(raw), &manifest); err != nil {
+			return nil, err
+		}
+
+		if len(manifest.Manifests) == 0 {
+			return manifest.Annotations, nil
+		}
+
+		raw, err = layout.File("blobs/" + strings.Replace(manifest.Manifests[0].Digest, ":", "/", 1)).Contents(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func healthcheckInstruction(config *imageConfig) string {
+	hc := config.Healthcheck
+	if len(hc.Test) == 0 || hc.Test[0] == "NONE" {
+		return "HEALTHCHECK NONE"
+	}
+
+	var opts []string
+	if hc.Interval > 0 {
+		opts = append(opts, "--interval="+hc.Interval.String())
+	}
+
+	if hc.Timeout > 0 {
+		opts = append(opts, "--timeout="+hc.Timeout.String())
+	}
+
+	if hc.StartPeriod > 0 {
+		opts = append(opts, "--start-period="+hc.StartPeriod.String())
+	}
+
+	if hc.Retries > 0 {
+		opts = append(opts, "--retries="+strconv.Itoa(hc.Retries))
+	}
+
+	cmd := toJSONList(hc.Test[1:])
+	if hc.Test[0] == "CMD-SHELL" {
+		cmd = strings.Join(hc.Test[1:], " ")
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("HEALTHCHECK %s CMD %s", strings.Join(opts, " "), cmd))
+}
+
+func toJSONList(vals []string) string {
+	out, _ := json.Marshal(vals)
+	return string(out)
+}
+
+// quote makes the value safe to use in the ENV and LABEL instructions
+func quote(val string) string {
+	return strings.ReplaceAll(strconv.Quote(val), "$", `\$`)
+}
+
+func contains(vals []string, val string) bool {
+	for _, v := range vals {
+		if v == val {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys[T any](vals map[string]T) []string {
+	keys := make([]string, 0, len(vals))
+	for key := range vals {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
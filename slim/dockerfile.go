@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+)
+
+const (
+	cmdXray   = "xray"
+	flagState = "--state-path"
+
+	xrayStatePath = "/xray-state"
+	xrayOutPath   = "/xray-out"
+
+	//the Dockerfile xray reconstructs from the image history (saved in the image artifacts)
+	reversedDockerfile = "Dockerfile.reversed"
+)
+
+// Reverse engineer the Dockerfile of a container image (reconstructed from the image history by mint xray)
+func (s *Slim) Dockerfile(
+	ctx context.Context,
+	container *Container,
+) (*File, error) {
+	dockerd, docker, err := s.startDockerd(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &slimRun{
+		docker: docker,
+		id:     newRunID(),
+	}
+
+	defer run.cleanup(ctx)
+
+	_, imgRef, err := run.loadImage(ctx, "target", container)
+	if err != nil {
+		return nil, err
+	}
+
+	xray, entrypoint, err := mintContainer(ctx, dockerd)
+	if err != nil {
+		return nil, err
+	}
+
+	cargs := append(entrypoint, flagState, xrayStatePath, cmdXray, "--target", imgRef)
+	state := xray.
+		WithExec(cargs, ContainerWithExecOpts{SkipEntrypoint: true}).
+		Directory(xrayStatePath)
+
+	//the mint image doesn't ship a shell and the usual tools, so the Dockerfile is looked up in the state with busybox.
+	//Synced before the image is removed from the dockerd
+	return dag.
+		Container().
+		From(toolsImage).
+		WithMountedDirectory(xrayStatePath, state).
+		WithExec([]string{"sh", "-c",
+			`file="$(find "$1" -name "$3" | head -n 1)" && [ -n "$file" ] && mkdir -p "$2" && cp "$file" "$2/Dockerfile"`,
+			"sh",
+			xrayStatePath,
+			xrayOutPath,
+			reversedDockerfile,
+		}).
+		File(xrayOutPath + "/Dockerfile").
+		Sync(ctx)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Categories of mint failures
+const (
+	FailureTimeout        = "timeout"
+	FailureInvalidOptions = "invalid-options"
+	FailureSensor         = "sensor"
+	FailureTargetStart    = "target-start"
+	FailureHttpProbe      = "http-probe"
+	FailureImageBuild     = "image-build"
+	FailureUnknown        = "unknown"
+
+	//number of log lines to keep around the line identifying the failure
+	excerptContext = 10
+
+	//mint exit codes combine the exit code type (of the command) with the command specific code
+	exitCodeTypeCommon = 0x01000000
+	exitCodeTypeBuild  = 0x02000000
+)
+
+// failures identified by the exit code mint reports when it exits
+var exitCodeFailures = map[int]string{
+	exitCodeTypeCommon | 3: FailureInvalidOptions, //bad network name
+	exitCodeTypeBuild | 2:  FailureInvalidOptions, //bad custom image tag
+	exitCodeTypeBuild | 3:  FailureImageBuild,
+	exitCodeTypeBuild | 6:  FailureTargetStart,    //no entrypoint
+	exitCodeTypeBuild | 7:  FailureInvalidOptions, //bad target compose service
+	exitCodeTypeBuild | 8:  FailureInvalidOptions, //compose service without image
+}
+
+const (
+	//reported by the run script when it stops mint
+	timeoutMessage = "slim: analysis timed out"
+
+	//reported by the mint CLI for unknown flags or bad flag values
+	usageErrorPrefix = "Incorrect Usage"
+)
+
+var exitCodePattern = regexp.MustCompile(`exit\.code=(-?\d+)`)
+
+var (
+	ErrTimeout        = errors.New("slim timed out")
+	ErrInvalidOptions = errors.New("invalid slim options")
+	ErrSensor         = errors.New("sensor failed")
+	ErrTargetStart    = errors.New("target container failed to start")
+	ErrHttpProbe      = errors.New("all HTTP probes failed")
+	ErrImageBuild     = errors.New("minified image build failed")
+	ErrUnknown        = errors.New("slim failed")
+)
+
+var failureErrors = map[string]error{
+	FailureTimeout:        ErrTimeout,
+	FailureInvalidOptions: ErrInvalidOptions,
+	FailureSensor:         ErrSensor,
+	FailureTargetStart:    ErrTargetStart,
+	FailureHttpProbe:      ErrHttpProbe,
+	FailureImageBuild:     ErrImageBuild,
+	FailureUnknown:        ErrUnknown,
+}
+
+// the patterns are checked (against the mint state and info lines) in the order mint goes through its stages
+var failurePatterns = []struct {
+	kind     string
+	patterns []string
+}{
+	{
+		kind: FailureInvalidOptions,
+		patterns: []string{
+			"param.error",
+		},
+	},
+	{
+		kind: FailureSensor,
+		patterns: []string{
+			"sensor.error",
+			"ipc.error",
+		},
+	},
+	{
+		kind: FailureTargetStart,
+		patterns: []string{
+			"container.start.error",
+			"target.container.exit",
+			"status=crashed",
+		},
+	},
+	{
+		kind: FailureHttpProbe,
+		patterns: []string{
+			"no.successful.calls",
+			"http.probe.error",
+		},
+	},
+	{
+		kind: FailureImageBuild,
+		patterns: []string{
+			"image.build.error",
+			"imagebuild.error",
+		},
+	},
+}
+
+// SlimError describes why mint failed to minify the target image
+type SlimError struct {
+	Kind     string
+	ExitCode int
+	Excerpt  string
+}
+
+func (e *SlimError) Error() string {
+	return fmt.Sprintf("%s [%s] (exit code %d):\n%s", failureErrors[e.Kind], e.Kind, e.ExitCode, e.Excerpt)
+}
+
+func (e *SlimError) Unwrap() error {
+	return failureErrors[e.Kind]
+}
+
+// classifyFailure maps the mint exit code and output to one of the failure categories.
+// Only the lines printed by mint (and the run script) are considered: the logs of the target container are skipped.
+func classifyFailure(exitCode int, output string) *SlimError {
+	lines := mintLines(output)
+
+	kind, idx := failureKind(lines)
+	if kind == "" {
+		return &SlimError{
+			Kind:     FailureUnknown,
+			ExitCode: exitCode,
+			Excerpt:  excerpt(lines, len(lines)-2*excerptContext, len(lines)),
+		}
+	}
+
+	return &SlimError{
+		Kind:     kind,
+		ExitCode: exitCode,
+		Excerpt:  excerpt(lines, idx-excerptContext, idx+excerptContext+1),
+	}
+}
+
+// failureKind returns the failure category and the index of the line identifying it
+// (the mint exit code is checked first, then the run script messages, the usage errors and the mint state and info lines)
+func failureKind(lines []string) (string, int) {
+	for idx, line := range lines {
+		if !isMintStateLine(line) {
+			continue
+		}
+
+		match := exitCodePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		code, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+
+		if kind, ok := exitCodeFailures[code]; ok {
+			return kind, idx
+		}
+	}
+
+	for idx, line := range lines {
+		switch {
+		case strings.HasPrefix(line, timeoutMessage):
+			return FailureTimeout, idx
+		case strings.HasPrefix(line, usageErrorPrefix):
+			return FailureInvalidOptions, idx
+		}
+	}
+
+	for _, fp := range failurePatterns {
+		for idx, line := range lines {
+			if !isMintStateLine(line) {
+				continue
+			}
+
+			for _, pattern := range fp.patterns {
+				if strings.Contains(line, pattern) {
+					return fp.kind, idx
+				}
+			}
+		}
+	}
+
+	return "", -1
+}
+
+// isMintStateLine reports whether the line is one of the mint state, info or error lines.
+// Example: cmd=slim state=error ...
+func isMintStateLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "cmd=") {
+		return false
+	}
+
+	for _, field := range []string{" state=", " info=", " error="} {
+		if strings.Contains(line, field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mintLines returns the output lines, without the target container logs mint shows (--show-clogs)
+func mintLines(output string) []string {
+	var lines []string
+	inClogs := false
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasSuffix(trimmed, clogsStdoutMarker), strings.HasSuffix(trimmed, clogsStderrMarker):
+			inClogs = true
+			continue
+		case strings.Contains(line, clogsEndMarker):
+			inClogs = false
+			continue
+		}
+
+		if !inClogs {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func excerpt(lines []string, from, to int) string {
+	if from < 0 {
+		from = 0
+	}
+
+	if to > len(lines) {
+		to = len(lines)
+	}
+
+	return strings.Join(lines[from:to], "\n")
+}
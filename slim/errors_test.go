@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		output   string
+		kind     string
+	}{
+		{
+			name:     "exit code",
+			exitCode: 3,
+			output: strings.Join([]string{
+				"cmd=slim state=started",
+				"cmd=slim state=error",
+				fmt.Sprintf("cmd=slim state=exited version=1.40.11 exit.code=%d", exitCodeTypeBuild|3),
+			}, "\n"),
+			kind: FailureImageBuild,
+		},
+		{
+			name:     "exit code before patterns",
+			exitCode: 7,
+			output: strings.Join([]string{
+				"cmd=slim info=http.probe.error error=timeout",
+				fmt.Sprintf("cmd=slim state=exited exit.code=%d", exitCodeTypeBuild|7),
+			}, "\n"),
+			kind: FailureInvalidOptions,
+		},
+		{
+			name:     "timeout",
+			exitCode: 143,
+			output: strings.Join([]string{
+				"cmd=slim state=started",
+				"slim: analysis timed out after 60 seconds",
+			}, "\n"),
+			kind: FailureTimeout,
+		},
+		{
+			name:     "usage error",
+			exitCode: 1,
+			output:   "Incorrect Usage: flag provided but not defined: -foo",
+			kind:     FailureInvalidOptions,
+		},
+		{
+			name:     "mint state line",
+			exitCode: 1,
+			output: strings.Join([]string{
+				"cmd=slim state=started",
+				"cmd=slim info=http.probe.summary status=no.successful.calls",
+				"cmd=slim state=exited",
+			}, "\n"),
+			kind: FailureHttpProbe,
+		},
+		{
+			name:     "target container logs are skipped",
+			exitCode: 1,
+			output: strings.Join([]string{
+				"cmd=slim state=started",
+				"cmd=slim info=container.logs container stdout:",
+				"cmd=slim info=http.probe.error invalid value",
+				"Incorrect Usage: app --help",
+				"slim: analysis timed out after 1 seconds",
+				"cmd=slim info=container.logs end of container logs",
+				"cmd=slim state=exited",
+			}, "\n"),
+			kind: FailureUnknown,
+		},
+		{
+			name:     "patterns outside mint lines are skipped",
+			exitCode: 1,
+			output:   "app: sensor.error ipc.error image.build.error",
+			kind:     FailureUnknown,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := classifyFailure(test.exitCode, test.output)
+			if err.Kind != test.kind {
+				t.Fatalf("kind = %q, want %q (excerpt: %q)", err.Kind, test.kind, err.Excerpt)
+			}
+
+			if err.ExitCode != test.exitCode {
+				t.Errorf("exit code = %d, want %d", err.ExitCode, test.exitCode)
+			}
+
+			if !errors.Is(err, failureErrors[test.kind]) {
+				t.Errorf("error %v doesn't wrap %v", err, failureErrors[test.kind])
+			}
+		})
+	}
+}
+
+func TestClassifyFailureExcerpt(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("cmd=slim info=line%d", i))
+	}
+	lines[25] = "cmd=slim state=error sensor.error"
+
+	err := classifyFailure(1, strings.Join(lines, "\n"))
+	want := strings.Join(lines[25-excerptContext:25+excerptContext+1], "\n")
+	if err.Excerpt != want {
+		t.Errorf("excerpt = %q, want %q", err.Excerpt, want)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	lines := []string{"a", "b", "c", "d"}
+	tests := []struct {
+		from, to int
+		want     string
+	}{
+		{1, 3, "b\nc"},
+		{-5, 2, "a\nb"},
+		{2, 10, "c\nd"},
+		{-5, 10, "a\nb\nc\nd"},
+	}
+
+	for _, test := range tests {
+		if got := excerpt(lines, test.from, test.to); got != test.want {
+			t.Errorf("excerpt(%d, %d) = %q, want %q", test.from, test.to, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	logFileContainerStdout = "container.stdout.log"
+	logFileContainerStderr = "container.stderr.log"
+	logFileHttpProbe       = "http-probe.log"
+
+	//markers mint uses when it shows the container logs (--show-clogs)
+	clogsStdoutMarker = "container stdout:"
+	clogsStderrMarker = "container stderr:"
+	clogsEndMarker    = "end of container logs"
+
+	httpProbeMarker = "http.probe"
+)
+
+// SlimResult is the outcome of a slim run, along with its logs
+type SlimResult struct {
+	// The slimmed container, or the original container (labeled as unslimmed, with the failure report) when slimming failed
+	Container *Container
+	// Whether slimming succeeded
+	Slimmed bool
+	// The logs of the run: the mint output, the stdout/stderr of the container used to perform dynamic inspection,
+	// the HTTP probe traffic, the exec probe results, the output of the probe container,
+	// the mint command line and the images in the dockerd before the run
+	Logs []*File
+}
+
+// Slim the container and return the logs of the run, retained even when slimming fails
+// (use SlimWithLogs to get the slimmed container from the same run)
+func (s *Slim) Logs(
+	ctx context.Context,
+	container *Container,
+	// Execution mode to use
+	// +optional
+	// +default="docker"
+	mode string,
+	// Enable running HTTP probes against the temporary container (test to false to disable)
+	// +optional
+	// +default=true
+	probeHttp bool,
+	// Probe HTTP - exit when all HTTP probe commands fail
+	// +optional
+	// +default=true
+	probeHttpExitOnFailure bool,
+	// Probe HTTP - comma separated subset of ports to probe
+	// +optional
+	probeHttpPorts string,
+	// Map all exposed ports to the same host ports analyzing image at runtime
+	// +optional
+	// +default=true
+	publishExposedPorts bool,
+	// Select when to start processing the collected telemetry - enter | signal | probe | exec | timeout-number-in-seconds | container.probe (can combine probe and exec like this: probe&exe)
+	// +optional
+	continueAfter string,
+	// Show container logs from the container used to perform dynamic inspection
+	// (the container stdout/stderr logs are only captured when mint shows them)
+	// +optional
+	// +default=true
+	showClogs bool,
+	// Show debugging information
+	// +optional
+	// +default=false
+	slimDebug bool,
+) ([]*File, error) {
+	result, err := s.SlimWithLogs(ctx,
+		container,
+		mode,
+		probeHttp,
+		probeHttpExitOnFailure,
+		probeHttpPorts,
+		publishExposedPorts,
+		continueAfter,
+		showClogs,
+		slimDebug)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Logs, nil
+}
+
+// Slim the container and return the result along with the logs of the same run.
+// The logs are returned even when slimming fails (the original container is returned then, labeled as unslimmed).
+func (s *Slim) SlimWithLogs(
+	ctx context.Context,
+	container *Container,
+	// Execution mode to use
+	// +optional
+	// +default="docker"
+	mode string,
+	// Enable running HTTP probes against the temporary container (test to false to disable)
+	// +optional
+	// +default=true
+	probeHttp bool,
+	// Probe HTTP - exit when all HTTP probe commands fail
+	// +optional
+	// +default=true
+	probeHttpExitOnFailure bool,
+	// Probe HTTP - comma separated subset of ports to probe
+	// +optional
+	probeHttpPorts string,
+	// Map all exposed ports to the same host ports analyzing image at runtime
+	// +optional
+	// +default=true
+	publishExposedPorts bool,
+	// Select when to start processing the collected telemetry - enter | signal | probe | exec | timeout-number-in-seconds | container.probe (can combine probe and exec like this: probe&exe)
+	// +optional
+	continueAfter string,
+	// Show container logs from the container used to perform dynamic inspection
+	// (the container stdout/stderr logs are only captured when mint shows them)
+	// +optional
+	// +default=true
+	showClogs bool,
+	// Show debugging information
+	// +optional
+	// +default=false
+	slimDebug bool,
+) (*SlimResult, error) {
+	run, slimmed, err := s.slim(ctx,
+		container,
+		mode,
+		probeHttp,
+		probeHttpExitOnFailure,
+		probeHttpPorts,
+		publishExposedPorts,
+		continueAfter,
+		showClogs,
+		slimDebug)
+	if run == nil {
+		return nil, err
+	}
+
+	if err != nil {
+		return &SlimResult{
+			Container: unslimmed(container, err),
+			Logs:      run.logs(),
+		}, nil
+	}
+
+	return &SlimResult{
+		Container: slimmed,
+		Slimmed:   true,
+		Logs:      run.logs(),
+	}, nil
+}
+
+func (r *slimRun) logs() []*File {
+	stdout, stderr := containerLogs(r.output)
+	return []*File{
+		r.mint.File(logsPath + "/" + logFileMint),
+		newFile(logFileContainerStdout, stdout),
+		newFile(logFileContainerStderr, stderr),
+		newFile(logFileHttpProbe, filterLines(r.output, httpProbeMarker)),
+		newFile(logFileExecProbe, r.execResults()),
+		newFile(logFileProbeContainer, r.probeOutput),
+		r.mint.File(logsPath + "/" + logFileCommand),
+		r.mint.File(logsPath + "/" + logFileImages),
+	}
+}
+
+// containerLogs extracts the stdout and stderr sections mint prints with the container logs
+func containerLogs(output string) (string, string) {
+	var stdout, stderr []string
+	var current *[]string
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasSuffix(strings.TrimSpace(line), clogsStdoutMarker):
+			current = &stdout
+			continue
+		case strings.HasSuffix(strings.TrimSpace(line), clogsStderrMarker):
+			current = &stderr
+			continue
+		case strings.Contains(line, clogsEndMarker):
+			current = nil
+			continue
+		}
+
+		if current != nil {
+			*current = append(*current, line)
+		}
+	}
+
+	return strings.Join(stdout, "\n"), strings.Join(stderr, "\n")
+}
+
+func filterLines(output, pattern string) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, pattern) {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func newFile(name, contents string) *File {
+	return dag.Directory().WithNewFile(name, contents).File(name)
+}
@@ -5,7 +5,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"path"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 	"encoding/json"
 )
 
@@ -16,8 +20,84 @@ const (
 	archAMD64      = "amd64"
 	archARM64      = "arm64"
 
-	outputImageTag = "slim-output:latest"
-	outputImageTar = "output.tar"
+	outputImageRepo = "slim-output"
+
+	//the images loaded in the dockerd get a per run tag in this repository
+	loadedImageRepo = "slim-input"
+
+	logsPath        = "/slim-logs"
+	logFileMint     = "mint.log"
+	logFileExitCode = "exit-code"
+	//the debug output (also printed) is kept in the logs
+	logFileImages  = "images.json"
+	logFileCommand = "mint-command.log"
+
+	defaultEngineVersion = "24.0"
+
+	//location of the host config (resource limits) for the container used to perform dynamic inspection
+	hostConfigPath = "/slim-config/host-config.json"
+
+	//location of the exec probe file (generated from the exec probe scripts) in the mint container
+	execProbeFilePath = "/slim-config/exec-probe.sh"
+
+	//staging location (in the dockerd host filesystem) for the mounted files and the compose projects
+	mountsHostPath = "/tmp/slim-mounts"
+
+	//the mint image doesn't ship a shell and the usual tools, so the run script uses its own (busybox)
+	toolsImage   = "index.docker.io/busybox:musl"
+	toolsPath    = "/slim-tools"
+	toolsApplets = "sh mkdir tee grep sleep kill"
+
+	//seconds mint gets to stop after the timeout, before it's killed
+	timeoutKillGrace = "10"
+
+	//runs mint (passed as the script params) capturing its output and exit code,
+	//so the logs are available even when mint fails or it's stopped after SLIM_TIMEOUT seconds.
+	//When there's a probe container script, it's run once mint waits for the signal to continue.
+	//On timeout, the probe container is removed and mint is stopped (and killed if it doesn't exit in time).
+	//The probe container is removed too when mint exits before it's done.
+	//The sleeps don't hold the output pipe, so tee doesn't wait for them.
+	runScript = `PATH="/slim-tools:$PATH"
+mkdir -p /slim-logs
+stop_probe() {
+  [ -n "$probe" ] || return 0
+  kill "$probe" 2>/dev/null
+  ` + dockerCliPath + ` rm -f "` + probeContainerPrefix + `$SLIM_RUN_ID" >/dev/null 2>&1
+}
+{
+  "$@" 2>&1 &
+  pid=$!
+  if [ -f /slim-config/probe-container.sh ]; then
+    (
+      until grep -q "continue.after" /slim-logs/mint.log 2>/dev/null; do
+        kill -0 "$pid" 2>/dev/null || exit 0
+        sleep 1 >/dev/null 2>&1
+      done
+      sh /slim-config/probe-container.sh > /slim-logs/probe-container.log 2>&1
+      echo $? > /slim-logs/probe-container.exit-code
+      kill -USR1 "$pid"
+    ) &
+    probe=$!
+  fi
+  if [ -n "$SLIM_TIMEOUT" ]; then
+    (
+      sleep "$SLIM_TIMEOUT" >/dev/null 2>&1
+      kill -0 "$pid" 2>/dev/null || exit 0
+      echo "slim: analysis timed out after $SLIM_TIMEOUT seconds"
+      stop_probe
+      kill "$pid" 2>/dev/null || exit 0
+      sleep ` + timeoutKillGrace + ` >/dev/null 2>&1
+      kill -0 "$pid" 2>/dev/null || exit 0
+      echo "slim: mint didn't stop, killing it"
+      kill -KILL "$pid" 2>/dev/null
+    ) &
+    watchdog=$!
+  fi
+  wait "$pid"
+  echo $? > /slim-logs/exit-code
+  [ -n "$watchdog" ] && kill "$watchdog" 2>/dev/null
+  kill -0 "$probe" 2>/dev/null && stop_probe
+} | tee /slim-logs/mint.log`
 
 	flagDebug = "--debug"
 	trueValue = "true"
@@ -31,11 +111,21 @@ const (
 	flagHttpProbeCmd           = "--http-probe-cmd"
 	flagHttpProbePorts         = "--http-probe-ports"
 	flagHttpProbeExitOnFailure = "--http-probe-exit-on-failure"
+	flagHttpProbeClientTimeout = "--http-probe-client-timeout"
+
+	flagCROHostConfigFile = "--cro-host-config-file"
 
 	flagPublishPort         = "--publish-port"
 	flagPublishExposedPorts = "--publish-exposed-ports"
 
-	flagExecProbe = "--exec"
+	flagExecProbe     = "--exec"
+	flagExecProbeFile = "--exec-file"
+
+	flagMount = "--mount"
+
+	flagComposeFile      = "--compose-file"
+	flagTargetComposeSvc = "--target-compose-svc"
+	flagComposeWorkdir   = "--compose-workdir"
 
 	flagIncludePath     = "--include-path"
 	flagIncludeBin      = "--include-bin"
@@ -49,6 +139,13 @@ const (
 	flagExpose          = "--expose"
 	flagContinueAfter   = "--continue-after"
 
+	flagNewLabel     = "--new-label"
+	flagRemoveLabel  = "--remove-label"
+	flagNewEnv       = "--new-env"
+	flagRemoveEnv    = "--remove-env"
+	flagNewExpose    = "--new-expose"
+	flagRemoveExpose = "--remove-expose"
+
 	flagSensorIPCMode     = "--sensor-ipc-mode"
 	flagSensorIPCEndpoint = "--sensor-ipc-endpoint"
 
@@ -73,9 +170,36 @@ type Slim struct {
 	imageBuildEngine  string
 	imageBuildArch    string
 	execProbes        []string
+	execProbeFiles    []*File
+	probeContainer    *Container
+	probeArgs         []string
 	httpProbeCmds     []string
 	exposePorts       []string
 	publishPorts      []string
+	newLabels         []string
+	removeLabels      []string
+	newEnvVars        []string
+	removeEnvVars     []string
+	newExposePorts    []string
+	removeExposePorts []string
+	mounts            []slimMount
+	outputRepository  string
+	outputTag         string
+	engine            *Service
+	engineVersion     string
+	enginePersist     *bool
+	engineNamespace   string
+	timeout           int
+	httpProbeTimeout  int
+	cpuLimit          string
+	memoryLimit       string
+}
+
+type slimMount struct {
+	path       string
+	dir        *Directory
+	file       *File
+	executable bool
 }
 
 func (s *Slim) Slim(
@@ -111,7 +235,254 @@ func (s *Slim) Slim(
 	// +optional
 	// +default=false
 	slimDebug bool,
+	// Return the original container (labeled as unslimmed, with a report describing the failure) when slimming fails
+	// +optional
+	// +default=false
+	fallbackOnFailure bool,
+) (*Container, error) {
+	_, slimmed, err := s.slim(ctx,
+		container,
+		mode,
+		probeHttp,
+		probeHttpExitOnFailure,
+		probeHttpPorts,
+		publishExposedPorts,
+		continueAfter,
+		showClogs,
+		slimDebug)
+	if err != nil {
+		if fallbackOnFailure {
+			return unslimmed(container, err), nil
+		}
+
+		return container, err
+	}
+
+	return slimmed, nil
+}
+
+// slim runs the analysis and exports the slimmed container (labeled with the report).
+// The run is returned even when slimming fails (unless mint didn't get to run), so its logs are available.
+func (s *Slim) slim(
+	ctx context.Context,
+	container *Container,
+	mode string,
+	probeHttp bool,
+	probeHttpExitOnFailure bool,
+	probeHttpPorts string,
+	publishExposedPorts bool,
+	continueAfter string,
+	showClogs bool,
+	slimDebug bool,
+) (*slimRun, *Container, error) {
+	run, err := s.run(ctx,
+		&slimTarget{container: container},
+		mode,
+		probeHttp,
+		probeHttpExitOnFailure,
+		probeHttpPorts,
+		publishExposedPorts,
+		continueAfter,
+		showClogs,
+		slimDebug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer run.cleanup(ctx)
+
+	if run.exitCode != 0 {
+		return run, nil, classifyFailure(run.exitCode, run.output)
+	}
+
+	restored, err := s.restoreConfig(ctx, run)
+	if err != nil {
+		return run, nil, err
+	}
+
+	slimmed, err := s.export(ctx, run)
+	if err != nil {
+		return run, nil, err
+	}
+
+	notRestored, err := missingAnnotations(ctx, container, slimmed)
+	if err != nil {
+		return run, nil, err
+	}
+
+	return run, withReport(slimmed, slimReport{
+		Status:        statusSlimmed,
+		Restored:      restored,
+		NotRestored:   notRestored,
+		ProbeExitCode: run.probeExitCode,
+	}), nil
+}
+
+func (s *Slim) Compare(
+	ctx context.Context,
+	container *Container,
+	// Execution mode to use
+	// +optional
+	// +default="docker"
+	mode string,
+	// Run HTTP probes against the temporary container
+	// +optional
+	// +default=true
+	probeHttp bool,
+	// Probe HTTP - exit on failure - TBD - add real desc
+	// +optional
+	// +default=true
+	probeHttpExitOnFailure bool,
+	// Probe HTTP - comma separated subset of ports to probe
+	// +optional
+	probeHttpPorts string,
+	// Probe HTTP - publish exposed ports - TBD - add real desc
+	// +optional
+	// +default=true
+	publishExposedPorts bool,
+	// Continue after mode - TBD - add real desc
+	// +optional
+	continueAfter string,
+	// Show temporary container logs - TBD - add real desc
+	// +optional
+	// +default=false
+	showClogs bool,
+	// Show debug messages - TBD - add real desc
+	// +optional
+	// +default=false
+	slimDebug bool,
 ) (*Container, error) {
+	slimmed, err := s.Slim(ctx,
+		container,
+		mode,
+		probeHttp,
+		probeHttpExitOnFailure,
+		probeHttpPorts,
+		publishExposedPorts,
+		continueAfter,
+		showClogs,
+		slimDebug,
+		false)
+	if err != nil {
+		return nil, err
+	}
+
+	debug := dag.
+		Container().
+		From("alpine").
+		WithMountedDirectory("before", slimmed.Rootfs()).
+		WithMountedDirectory("after", container.Rootfs())
+	return debug, nil
+}
+
+func (r *slimRun) outputRef() string {
+	return r.outputRepository + ":" + r.outputTag
+}
+
+// export extracts the resulting image back into a container
+func (s *Slim) export(ctx context.Context, run *slimRun) (*Container, error) {
+	return run.docker.Image(DockerCliImageOpts{
+		Repository: run.outputRepository,
+		Tag:        run.outputTag,
+	}).Export().Sync(ctx)
+}
+
+// cleanup removes the images the run added to the dockerd, including the output image
+// (unless its name is set explicitly). It's used on the failure paths too,
+// so the images that don't exist (e.g. the output image when mint failed) are ignored.
+func (r *slimRun) cleanup(ctx context.Context) {
+	refs := r.images
+	if !r.keepOutput && r.outputRepository != "" {
+		refs = append(refs, r.outputRef())
+	}
+
+	if len(refs) == 0 {
+		return
+	}
+
+	//the images mint built (and the config restore rebuilt on top of) are removed along with the output
+	_, _ = r.docker.
+		Container().
+		WithEnvVariable("SLIM_RUN_ID", r.id).
+		WithExec(append([]string{"sh", "-c", `docker image rm "$@" >/dev/null 2>&1; true`, "sh"}, refs...)).
+		Sync(ctx)
+}
+
+// slimDefault slims the container with the default options
+func (s *Slim) slimDefault(ctx context.Context, container *Container) (*Container, error) {
+	return s.Slim(ctx,
+		container,
+		modeDocker,
+		true,
+		true,
+		"",
+		true,
+		"",
+		false,
+		false,
+		false)
+}
+
+// slimTarget is what mint analyzes: a container image or a service in a compose project
+type slimTarget struct {
+	container   *Container
+	project     *Directory
+	composeFile string
+	service     string
+}
+
+// slimRun holds the state of a mint execution
+type slimRun struct {
+	docker *DockerCli
+	id     string
+	//the images loaded in the dockerd for the run (removed by cleanup)
+	images           []string
+	imageID          string
+	outputRepository string
+	outputTag        string
+	keepOutput       bool
+	mint             *Container
+	exitCode         int
+	output           string
+	execCommands     []string
+	probeExitCode    *int
+	probeOutput      string
+}
+
+func (s *Slim) run(
+	ctx context.Context,
+	target *slimTarget,
+	// Execution mode to use
+	// +optional
+	// +default="docker"
+	mode string,
+	// Enable running HTTP probes against the temporary container (test to false to disable)
+	// +optional
+	// +default=true
+	probeHttp bool,
+	// Probe HTTP - exit when all HTTP probe commands fail
+	// +optional
+	// +default=true
+	probeHttpExitOnFailure bool,
+	// Probe HTTP - comma separated subset of ports to probe
+	// +optional
+	probeHttpPorts string,
+	// Map all exposed ports to the same host ports analyzing image at runtime
+	// +optional
+	// +default=true
+	publishExposedPorts bool,
+	// Select when to start processing the collected telemetry - enter | signal | probe | exec | timeout-number-in-seconds | container.probe (can combine probe and exec like this: probe&exe)
+	// +optional
+	continueAfter string,
+	// Show container logs from the container used to perform dynamic inspection
+	// +optional
+	// +default=false
+	showClogs bool,
+	// Show debugging information
+	// +optional
+	// +default=false
+	slimDebug bool,
+) (_ *slimRun, err error) {
 	switch mode {
 	case modeDocker, modeNative:
 	default:
@@ -122,49 +493,85 @@ func (s *Slim) Slim(
 		return nil, fmt.Errorf("unsupported mode - %s", mode)
 	}
 
-	// Start an ephemeral dockerd
-	dockerd := dag.Docker().Engine()
-	docker := dag.Docker().Cli(DockerCliOpts{
-		Engine: dockerd,
-	})
+	//the probe container controls when the analysis continues
+	if s.probeContainer != nil && continueAfter != "" && continueAfter != continueAfterSignal {
+		return nil, fmt.Errorf("continue after (%s) can't be used with a probe container", continueAfter)
+	}
+
+	dockerd, docker, err := s.startDockerd(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	//////
 	imgListBefore, err := docker.Images(ctx)
 	if err != nil {
-		return container, err
+		return nil, err
 	}
 
 	fmt.Printf("IMG LIST (BEFORE): %s\n\n", toString(imgListBefore, true))
 	//////
 
-	// Load the input container into the dockerd
-	importedImg := docker.Import(container)
-	/*
-	importedImgRef, err := docker.Import(container)
-	if err != nil {
-		return container, err
+	run := &slimRun{
+		docker:     docker,
+		id:         newRunID(),
+		keepOutput: s.outputRepository != "",
 	}
-	*/
 
-	imageID, err := importedImg.LocalID(ctx)
-	if err != nil {
-		return container, err
+	//the images the run added to the dockerd are removed when it fails (otherwise, once the result is exported)
+	defer func() {
+		if err != nil {
+			run.cleanup(ctx)
+		}
+	}()
+
+	var targetArgs []string
+	imageID := ""
+	//image used to create the throwaway containers staging files in the dockerd host filesystem
+	helperRef := ""
+	projectPath := ""
+	if target.container != nil {
+		imageID, helperRef, err = run.loadImage(ctx, "target", target.container)
+		if err != nil {
+			return nil, err
+		}
+
+		targetArgs = []string{"--target", helperRef}
+	} else {
+		//there's no target image in the engine, so a small helper image is loaded instead
+		_, helperRef, err = run.loadImage(ctx, "helper", dag.Container().From("alpine"))
+		if err != nil {
+			return nil, err
+		}
+
+		//mint starts the compose services in the dockerd, so the project is staged in the dockerd host filesystem
+		//(and mounted at the same path in the mint container): the relative bind mounts resolve on both sides
+		projectPath, err = run.stage(ctx, helperRef, "compose-project", slimMount{dir: target.project})
+		if err != nil {
+			return nil, err
+		}
+
+		targetArgs = []string{
+			flagComposeFile, projectPath + "/" + target.composeFile,
+			flagTargetComposeSvc, target.service,
+			flagComposeWorkdir, projectPath,
+		}
 	}
 
-	/*
-	imgList, err := docker.Images(ctx)
-	if err != nil {
-		return container, err
+	run.imageID = imageID
+	run.outputRepository, run.outputTag = s.outputRepository, s.outputTag
+	if run.outputRepository == "" {
+		run.outputRepository = outputImageRepo
+		if imageID != "" {
+			run.outputTag = outputTagFor(imageID, run.id)
+		} else {
+			run.outputTag = outputTagFor(target.service, run.id)
+		}
 	}
 
-	fmt.Printf("IMG LIST: %s\n\n", toString(imgList, true))
-	*/
-
-	imgRef, err := docker.Image(DockerCliImageOpts{
-		LocalID: imageID,
-	}).Ref(ctx)
+	mountArgs, err := s.stageMounts(ctx, run, helperRef)
 	if err != nil {
-		return container, err
+		return nil, err
 	}
 
 	var cargs []string
@@ -174,9 +581,8 @@ func (s *Slim) Slim(
 
 	cargs = append(cargs, cmdSlim)
 	cargs = append(cargs, "--tag")
-	cargs = append(cargs, outputImageTag)
-	cargs = append(cargs, "--target")
-	cargs = append(cargs, imgRef)
+	cargs = append(cargs, run.outputRef())
+	cargs = append(cargs, targetArgs...)
 
 	if showClogs {
 		cargs = append(cargs, flagShowClogs)
@@ -199,11 +605,26 @@ func (s *Slim) Slim(
 		cargs = append(cargs, flagPublishPort, val)
 	}
 
+	for _, val := range mountArgs {
+		cargs = append(cargs, flagMount, val)
+	}
+
 	for _, val := range s.httpProbeCmds {
 		cargs = append(cargs, flagHttpProbeCmd, val)
 	}
 
-	if len(s.execProbes) > 0 {
+	execProbeFile, execCommands, execMountArgs, err := s.execProbeFile(ctx, run, helperRef)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, val := range execMountArgs {
+		cargs = append(cargs, flagMount, val)
+	}
+
+	if execProbeFile != "" {
+		cargs = append(cargs, flagExecProbeFile, execProbeFilePath)
+	} else if len(s.execProbes) > 0 {
 		//todo: support multiple exec probes (using the first one for now)
 		cargs = append(cargs, flagExecProbe, s.execProbes[0])
 	}
@@ -232,6 +653,40 @@ func (s *Slim) Slim(
 		cargs = append(cargs, flagEnv, val)
 	}
 
+	for _, val := range s.newLabels {
+		cargs = append(cargs, flagNewLabel, val)
+	}
+
+	for _, val := range s.removeLabels {
+		cargs = append(cargs, flagRemoveLabel, val)
+	}
+
+	for _, val := range s.newEnvVars {
+		cargs = append(cargs, flagNewEnv, val)
+	}
+
+	for _, val := range s.removeEnvVars {
+		cargs = append(cargs, flagRemoveEnv, val)
+	}
+
+	for _, val := range s.newExposePorts {
+		cargs = append(cargs, flagNewExpose, val)
+	}
+
+	for _, val := range s.removeExposePorts {
+		cargs = append(cargs, flagRemoveExpose, val)
+	}
+
+	probeScript, err := s.probeContainerScript(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+
+	if probeScript != "" {
+		//the run script signals mint once the probe container is done
+		continueAfter = continueAfterSignal
+	}
+
 	if continueAfter != "" {
 		cargs = append(cargs, flagContinueAfter, continueAfter)
 	}
@@ -256,6 +711,19 @@ func (s *Slim) Slim(
 		cargs = append(cargs, flagRTASourcePT, fmt.Sprintf("%v", *s.rtaSourcePT))
 	}
 
+	if s.httpProbeTimeout > 0 {
+		cargs = append(cargs, flagHttpProbeClientTimeout, fmt.Sprintf("%d", s.httpProbeTimeout))
+	}
+
+	hostConfig, err := s.hostConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if hostConfig != "" {
+		cargs = append(cargs, flagCROHostConfigFile, hostConfigPath)
+	}
+
 	if s.includeZoneinfo != nil {
 		cargs = append(cargs, flagIncludeZoneInfo, fmt.Sprintf("%v", *s.includeZoneinfo))
 	}
@@ -274,80 +742,85 @@ func (s *Slim) Slim(
 	}
 
 	// Setup the slim container, attached to the dockerd
-	slim := dag.
-		Container().
-		From(engineImage()).
-		WithServiceBinding("dockerd", dockerd).
-		WithEnvVariable("DOCKER_HOST", "tcp://dockerd:2375").
-		WithExec(cargs)
+	slim, entrypoint, err := mintContainer(ctx, dockerd)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.project != nil {
+		slim = slim.WithMountedDirectory(projectPath, target.project)
+	}
+
+	if probeScript != "" {
+		slim = slim.
+			WithFile(dockerCliPath, dag.Container().From(dockerCliImage).File(dockerCliPath)).
+			WithNewFile(probeScriptPath, ContainerWithNewFileOpts{
+				Contents: probeScript,
+			})
+	}
+
+	if execProbeFile != "" {
+		slim = slim.WithNewFile(execProbeFilePath, ContainerWithNewFileOpts{
+			Contents: execProbeFile,
+		})
+	}
+
+	if hostConfig != "" {
+		slim = slim.WithNewFile(hostConfigPath, ContainerWithNewFileOpts{
+			Contents: hostConfig,
+		})
+	}
+
+	if s.timeout > 0 {
+		slim = slim.WithEnvVariable("SLIM_TIMEOUT", fmt.Sprintf("%d", s.timeout))
+	}
+
+	slim = slim.
+		WithNewFile(logsPath+"/"+logFileImages, ContainerWithNewFileOpts{
+			Contents: toString(imgListBefore, true),
+		}).
+		WithNewFile(logsPath+"/"+logFileCommand, ContainerWithNewFileOpts{
+			Contents: strings.Join(append(entrypoint, cargs...), " ") + "\n",
+		}).
+		//the analysis observes the target at runtime, so a (possibly failed) run is never reused from the cache
+		WithEnvVariable("SLIM_RUN_ID", run.id).
+		WithExec(
+			append([]string{toolsPath + "/sh", "-c", runScript, "sh"}, append(entrypoint, cargs...)...),
+			ContainerWithExecOpts{SkipEntrypoint: true})
 
 	// Force execution of the slim command
 	slim, err = slim.Sync(ctx)
 	if err != nil {
-		return container, err
+		return nil, err
 	}
 
-	// Extract the resulting image back into a container
-	return docker.Image(DockerCliImageOpts{
-		Repository: "slim-output",
-		Tag:        "latest",
-	}).Export(), nil
-}
+	rawExitCode, err := slim.File(logsPath + "/" + logFileExitCode).Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *Slim) Compare(
-	ctx context.Context,
-	container *Container,
-	// Execution mode to use
-	// +optional
-	// +default="docker"
-	mode string,
-	// Run HTTP probes against the temporary container
-	// +optional
-	// +default=true
-	probeHttp bool,
-	// Probe HTTP - exit on failure - TBD - add real desc
-	// +optional
-	// +default=true
-	probeHttpExitOnFailure bool,
-	// Probe HTTP - comma separated subset of ports to probe
-	// +optional
-	probeHttpPorts string,
-	// Probe HTTP - publish exposed ports - TBD - add real desc
-	// +optional
-	// +default=true
-	publishExposedPorts bool,
-	// Continue after mode - TBD - add real desc
-	// +optional
-	continueAfter string,
-	// Show temporary container logs - TBD - add real desc
-	// +optional
-	// +default=false
-	showClogs bool,
-	// Show debug messages - TBD - add real desc
-	// +optional
-	// +default=false
-	slimDebug bool,
-) (*Container, error) {
-	slimmed, err := s.Slim(ctx,
-		container,
-		mode,
-		probeHttp,
-		probeHttpExitOnFailure,
-		probeHttpPorts,
-		publishExposedPorts,
-		continueAfter,
-		showClogs,
-		slimDebug)
+	exitCode, err := strconv.Atoi(strings.TrimSpace(rawExitCode))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected mint exit code - %q", rawExitCode)
+	}
+
+	output, err := slim.File(logsPath + "/" + logFileMint).Contents(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	debug := dag.
-		Container().
-		From("alpine").
-		WithMountedDirectory("before", slimmed.Rootfs()).
-		WithMountedDirectory("after", container.Rootfs())
-	return debug, nil
+	probeExitCode, probeOutput, err := probeContainerResult(ctx, slim)
+	if err != nil {
+		return nil, err
+	}
+
+	run.mint = slim
+	run.exitCode = exitCode
+	run.output = output
+	run.execCommands = execCommands
+	run.probeExitCode = probeExitCode
+	run.probeOutput = probeOutput
+	return run, nil
 }
 
 // MORE OPTIONAL PARAMS
@@ -427,6 +900,29 @@ func (s *Slim) WithExecProbe(val string) *Slim {
 	return s
 }
 
+// Exec probe from a file: a script (starting with #!, run by path) or a list of commands
+// (one complete command per line, each run in its own subshell)
+// executed in the container used to perform dynamic inspection. Each command result is reported in the logs.
+func (s *Slim) WithExecProbeFile(script *File) *Slim {
+	s.execProbeFiles = append(s.execProbeFiles, script)
+	return s
+}
+
+// Probe the target with a test container instead of (or along with) the HTTP probes.
+// The test container runs in the dockerd (using the host network, so it reaches the target on its published ports)
+// once the target is ready, and the analysis continues when it exits. Its exit code is included in the report.
+// It can't be combined with a continue after mode (other than signal, used for the probe container).
+func (s *Slim) WithProbeContainer(
+	tests *Container,
+	// Arguments for the test container
+	// +optional
+	args []string,
+) *Slim {
+	s.probeContainer = tests
+	s.probeArgs = args
+	return s
+}
+
 func (s *Slim) WithHttpProbeCmd(val string) *Slim {
 	s.httpProbeCmds = append(s.httpProbeCmds, val)
 	return s
@@ -442,6 +938,122 @@ func (s *Slim) WithPublishPort(val string) *Slim {
 	return s
 }
 
+// Mount a directory into the container used to perform dynamic inspection
+func (s *Slim) WithMount(path string, dir *Directory) *Slim {
+	s.mounts = append(s.mounts, slimMount{path: path, dir: dir})
+	return s
+}
+
+// Mount a file into the container used to perform dynamic inspection
+func (s *Slim) WithMountFile(path string, file *File) *Slim {
+	s.mounts = append(s.mounts, slimMount{path: path, file: file})
+	return s
+}
+
+// Set the repository and tag of the minified image in the dockerd.
+// The image is kept in the dockerd (by default, the output gets a unique per run tag and it's removed after the export).
+func (s *Slim) WithOutputImage(
+	repository string,
+	// +optional
+	// +default="latest"
+	tag string,
+) *Slim {
+	s.outputRepository = repository
+	s.outputTag = tag
+	return s
+}
+
+// ENGINE
+
+// Use an existing Docker Engine (listening on tcp port 2375) instead of starting one
+func (s *Slim) WithEngine(engine *Service) *Slim {
+	s.engine = engine
+	return s
+}
+
+// Docker Engine version to start (default: 24.0)
+func (s *Slim) WithEngineVersion(val string) *Slim {
+	s.engineVersion = val
+	return s
+}
+
+// Persist the state of the started engine in a cache volume (default: true)
+func (s *Slim) WithEnginePersist(val bool) *Slim {
+	s.enginePersist = &val
+	return s
+}
+
+// Namespace for persisting the state of the started engine
+func (s *Slim) WithEngineNamespace(val string) *Slim {
+	s.engineNamespace = val
+	return s
+}
+
+// LIMITS
+
+// Stop the analysis (failing with a timeout error) if it takes longer than the given number of seconds.
+// The probe container is removed, and mint is killed if it doesn't stop within 10 seconds.
+func (s *Slim) WithTimeout(val int) *Slim {
+	s.timeout = val
+	return s
+}
+
+// Timeout (in seconds) for each HTTP probe call
+func (s *Slim) WithHttpProbeTimeout(val int) *Slim {
+	s.httpProbeTimeout = val
+	return s
+}
+
+// CPU limit for the container used to perform dynamic inspection (number of CPUs, e.g. 1.5)
+func (s *Slim) WithCpuLimit(val string) *Slim {
+	s.cpuLimit = val
+	return s
+}
+
+// Memory limit for the container used to perform dynamic inspection (e.g. 512m or 2g)
+func (s *Slim) WithMemoryLimit(val string) *Slim {
+	s.memoryLimit = val
+	return s
+}
+
+// OUTPUT IMAGE METADATA
+
+// Add a label to the minified image (format: key=value)
+func (s *Slim) WithNewLabel(val string) *Slim {
+	s.newLabels = append(s.newLabels, val)
+	return s
+}
+
+// Remove a label (by key) from the minified image
+func (s *Slim) WithRemoveLabel(val string) *Slim {
+	s.removeLabels = append(s.removeLabels, val)
+	return s
+}
+
+// Add an environment variable to the minified image (format: name=value)
+func (s *Slim) WithNewEnv(val string) *Slim {
+	s.newEnvVars = append(s.newEnvVars, val)
+	return s
+}
+
+// Remove an environment variable (by name) from the minified image
+func (s *Slim) WithRemoveEnv(val string) *Slim {
+	s.removeEnvVars = append(s.removeEnvVars, val)
+	return s
+}
+
+// Add an exposed port to the minified image (format: port[/protocol])
+func (s *Slim) WithNewExpose(val string) *Slim {
+	s.newExposePorts = append(s.newExposePorts, val)
+	return s
+}
+
+// Remove an exposed port from the minified image (format: port[/protocol])
+func (s *Slim) WithRemoveExpose(val string) *Slim {
+	s.removeExposePorts = append(s.removeExposePorts, val)
+	return s
+}
+
 // SUPPORTING FUNCTIONS:
 
 func engineImage() string {
@@ -455,6 +1067,239 @@ func engineImage() string {
 	}
 }
 
+// startDockerd starts the Docker Engine and waits for its API to be ready (mint is bound to the engine directly).
+// It's kept running for the whole run, so the state of a non persistent engine isn't lost between the steps.
+func (s *Slim) startDockerd(ctx context.Context) (*Service, *DockerCli, error) {
+	dockerd, err := s.dockerd().Start(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	docker := dag.Docker().Cli(DockerCliOpts{
+		Engine: dockerd,
+	})
+
+	if _, err := docker.WaitReady(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return dockerd, docker, nil
+}
+
+// dockerd returns the Docker Engine to run mint against
+func (s *Slim) dockerd() *Service {
+	if s.engine != nil {
+		return s.engine
+	}
+
+	version := s.engineVersion
+	if version == "" {
+		version = defaultEngineVersion
+	}
+
+	return dag.Docker().Engine(DockerEngineOpts{
+		Version:   version,
+		Namespace: s.engineNamespace,
+		Ephemeral: s.enginePersist != nil && !*s.enginePersist,
+	})
+}
+
+// hostConfig returns the (JSON encoded) docker host config with the resource limits
+// for the container used to perform dynamic inspection (empty if there are no limits)
+func (s *Slim) hostConfig() (string, error) {
+	var config struct {
+		NanoCpus int64 `json:",omitempty"`
+		Memory   int64 `json:",omitempty"`
+	}
+
+	if s.cpuLimit != "" {
+		cpus, err := strconv.ParseFloat(s.cpuLimit, 64)
+		if err != nil || cpus <= 0 {
+			return "", fmt.Errorf("invalid CPU limit - %s", s.cpuLimit)
+		}
+
+		config.NanoCpus = int64(cpus * 1e9)
+	}
+
+	if s.memoryLimit != "" {
+		memory, err := parseMemory(s.memoryLimit)
+		if err != nil {
+			return "", err
+		}
+
+		config.Memory = memory
+	}
+
+	if config.NanoCpus == 0 && config.Memory == 0 {
+		return "", nil
+	}
+
+	return toString(config, false), nil
+}
+
+// parseMemory parses a memory size in bytes, with an optional (binary) unit suffix - b | k | m | g
+func parseMemory(val string) (int64, error) {
+	units := map[string]int64{
+		"b": 1,
+		"k": 1 << 10,
+		"m": 1 << 20,
+		"g": 1 << 30,
+	}
+
+	num := strings.ToLower(strings.TrimSpace(val))
+	multiplier := int64(1)
+	if len(num) > 0 {
+		if m, ok := units[num[len(num)-1:]]; ok {
+			multiplier = m
+			num = num[:len(num)-1]
+		}
+	}
+
+	size, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid memory limit - %s", val)
+	}
+
+	return size * multiplier, nil
+}
+
+// mintContainer returns the mint container (with the tools the run script needs) attached to the dockerd,
+// and the command to run mint
+func mintContainer(ctx context.Context, dockerd *Service) (*Container, []string, error) {
+	engine := dag.Container().From(engineImage())
+	entrypoint, err := engine.Entrypoint(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(entrypoint) == 0 {
+		entrypoint = []string{"mint"}
+	}
+
+	tools := dag.
+		Container().
+		From(toolsImage).
+		WithExec([]string{"sh", "-c", `mkdir "$0" && cp /bin/busybox "$0" && for applet in $1; do ln -s busybox "$0/$applet"; done`, toolsPath, toolsApplets}).
+		Directory(toolsPath)
+
+	return engine.
+		WithDirectory(toolsPath, tools).
+		WithServiceBinding("dockerd", dockerd).
+		WithEnvVariable("DOCKER_HOST", "tcp://dockerd:2375"), entrypoint, nil
+}
+
+// loadImage loads the container into the dockerd, tagged with a per run reference (removed by cleanup),
+// and returns the image ID and reference.
+// The image is loaded again for each run: it may be gone from the engine (ephemeral engine, removed images).
+func (r *slimRun) loadImage(ctx context.Context, name string, container *Container) (string, string, error) {
+	imgRef := fmt.Sprintf("%s:%s-%s", loadedImageRepo, name, r.id)
+	script := `id=$(docker load -q -i /slim-load/image.tar | sed -n 's/^Loaded image ID: //p') &&
+[ -n "$id" ] &&
+docker tag "$id" "$1" &&
+echo "$id"`
+
+	out, err := r.docker.
+		Container().
+		WithMountedFile("/slim-load/image.tar", container.AsTarball()).
+		WithEnvVariable("SLIM_RUN_ID", r.id).
+		WithExec([]string{"sh", "-c", script, "sh", imgRef}).
+		Stdout(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	imageID := strings.TrimSpace(out)
+	if imageID == "" {
+		return "", "", fmt.Errorf("docker load failed or went undetected")
+	}
+
+	r.images = append(r.images, imgRef)
+	return imageID, imgRef, nil
+}
+
+// newRunID returns an ID unique per run, so concurrent or repeated runs sharing the engine don't clobber each other
+func newRunID() string {
+	return fmt.Sprintf("%d", time.Now().UnixNano())
+}
+
+// outputTagFor derives the output image tag from the input (image digest or compose service)
+func outputTagFor(input, runID string) string {
+	input = strings.TrimPrefix(input, "sha256:")
+	if len(input) > 12 {
+		input = input[:12]
+	}
+
+	return input + "-" + runID
+}
+
+// stageMounts copies the mounted directories and files into the dockerd host filesystem
+// and returns the matching mint mount params
+func (s *Slim) stageMounts(ctx context.Context, run *slimRun, helperRef string) ([]string, error) {
+	var margs []string
+	for idx, m := range s.mounts {
+		hostPath, err := run.stage(ctx, helperRef, fmt.Sprintf("mount-%d", idx), m)
+		if err != nil {
+			return nil, err
+		}
+
+		margs = append(margs, fmt.Sprintf("%s:%s", hostPath, m.path))
+	}
+
+	return margs, nil
+}
+
+// stage copies a directory or a file into the dockerd host filesystem
+// (using a throwaway container created from the helper image) and returns its host path.
+// The files are staged for each run (the dockerd host filesystem doesn't outlive the engine),
+// in a per run location, so concurrent runs sharing the engine don't clobber each other.
+func (r *slimRun) stage(ctx context.Context, helperRef string, name string, m slimMount) (string, error) {
+	helperName := fmt.Sprintf("slim-stage-%s-%s", r.id, name)
+	hostPath := fmt.Sprintf("%s/%s/%s", mountsHostPath, r.id, name)
+	volumePath := hostPath
+
+	cli := r.docker.Container()
+	src := "/slim-stage-src"
+	dst := "/slim-stage"
+	if m.dir != nil {
+		cli = cli.WithMountedDirectory(src, m.dir)
+		src = src + "/."
+	} else {
+		fileName := path.Base(m.path)
+		if m.executable {
+			//docker cp keeps the file mode
+			cli = cli.WithFile(src+"/"+fileName, m.file, ContainerWithFileOpts{Permissions: 0755})
+		} else {
+			cli = cli.WithMountedFile(src+"/"+fileName, m.file)
+		}
+		src = src + "/" + fileName
+		dst = dst + "/" + fileName
+		hostPath = hostPath + "/" + fileName
+	}
+
+	//the throwaway container is removed even when the copy fails
+	script := `docker create --name "$1" --entrypoint "" -v "$2:/slim-stage" "$3" true >/dev/null &&
+docker cp "$4" "$1:$5"
+status=$?
+docker rm -f "$1" >/dev/null 2>&1
+exit $status`
+
+	_, err := cli.
+		//the staged files live in the dockerd host filesystem, so they are staged again for each run
+		WithEnvVariable("SLIM_RUN_ID", r.id).
+		WithExec([]string{"sh", "-c", script, "sh",
+			helperName,
+			volumePath,
+			helperRef,
+			src,
+			dst}).
+		Sync(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return hostPath, nil
+}
+
 func toString(input interface{}, pretty bool) string {
 	var out bytes.Buffer
 	encoder := json.NewEncoder(&out)
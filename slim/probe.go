@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	logFileExecProbe      = "exec-probe.log"
+	logFileProbeContainer = "probe-container.log"
+	logFileProbeExitCode  = "probe-container.exit-code"
+
+	execResultMarker = "slim.exec.result"
+
+	continueAfterSignal = "signal"
+
+	//the docker CLI is added to the mint container to run the probe container
+	dockerCliImage = "index.docker.io/docker:cli"
+	dockerCliPath  = "/usr/local/bin/docker"
+
+	probeScriptPath = "/slim-config/probe-container.sh"
+
+	//the probe container is named after the run (so it can be removed on timeout)
+	probeContainerPrefix = "slim-probe-"
+
+	//location of the exec probe scripts (with a shebang) in the target container
+	execProbeScriptsPath = "/slim-exec-probes"
+)
+
+var execResultPattern = regexp.MustCompile(execResultMarker + ` index=(\d+) exit=(\d+)`)
+
+// execProbeFile generates the mint exec file from the exec probe scripts (and the exec probe commands),
+// reporting the exit code of each command. It returns the file contents, the commands (by index)
+// and the mint mount params for the scripts staged in the dockerd host filesystem.
+//
+// The scripts with a shebang are mounted in the target container and run by path (reported as a single command).
+// The other scripts are command lists: one complete command per line (no line continuations or multi-line commands),
+// each one run in its own subshell, so an 'exit' or a 'set -e' doesn't skip the following commands.
+func (s *Slim) execProbeFile(ctx context.Context, run *slimRun, helperRef string) (string, []string, []string, error) {
+	if len(s.execProbeFiles) == 0 {
+		return "", nil, nil, nil
+	}
+
+	var commands []string
+	var margs []string
+	for idx, script := range s.execProbeFiles {
+		contents, err := script.Contents(ctx)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		if strings.HasPrefix(contents, "#!") {
+			scriptPath := fmt.Sprintf("%s/%d/probe", execProbeScriptsPath, idx)
+			hostPath, err := run.stage(ctx, helperRef, fmt.Sprintf("exec-probe-%d", idx), slimMount{
+				path:       scriptPath,
+				file:       script,
+				executable: true,
+			})
+			if err != nil {
+				return "", nil, nil, err
+			}
+
+			margs = append(margs, hostPath+":"+scriptPath)
+			commands = append(commands, scriptPath)
+			continue
+		}
+
+		for _, line := range strings.Split(contents, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			commands = append(commands, line)
+		}
+	}
+
+	commands = append(commands, s.execProbes...)
+
+	var out strings.Builder
+	for idx, cmd := range commands {
+		fmt.Fprintf(&out, "( %s )\necho \"%s index=%d exit=$?\"\n", cmd, execResultMarker, idx)
+	}
+
+	return out.String(), commands, margs, nil
+}
+
+// execResults maps the exec probe results in the mint output to the commands
+func (r *slimRun) execResults() string {
+	var results []string
+	for _, match := range execResultPattern.FindAllStringSubmatch(r.output, -1) {
+		var idx int
+		fmt.Sscanf(match[1], "%d", &idx)
+		if idx >= len(r.execCommands) {
+			continue
+		}
+
+		results = append(results, fmt.Sprintf("exit=%s command=%q", match[2], r.execCommands[idx]))
+	}
+
+	return strings.Join(results, "\n")
+}
+
+// probeContainerScript loads the probe container into the dockerd
+// and returns the script running it (empty if there's no probe container)
+func (s *Slim) probeContainerScript(ctx context.Context, run *slimRun) (string, error) {
+	if s.probeContainer == nil {
+		return "", nil
+	}
+
+	_, imgRef, err := run.loadImage(ctx, "probe", s.probeContainer)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"docker", "run", "--rm", "--name", probeContainerPrefix + run.id, "--network", "host", imgRef}
+	args = append(args, s.probeArgs...)
+	for idx, arg := range args {
+		args[idx] = shellQuote(arg)
+	}
+
+	return "exec " + strings.Join(args, " ") + "\n", nil
+}
+
+// probeContainerResult returns the exit code and output of the probe container (if it ran)
+func probeContainerResult(ctx context.Context, slim *Container) (*int, string, error) {
+	entries, err := slim.Directory(logsPath).Entries(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !contains(entries, logFileProbeExitCode) {
+		return nil, "", nil
+	}
+
+	rawExitCode, err := slim.File(logsPath + "/" + logFileProbeExitCode).Contents(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	exitCode, err := strconv.Atoi(strings.TrimSpace(rawExitCode))
+	if err != nil {
+		return nil, "", fmt.Errorf("unexpected probe container exit code - %q", rawExitCode)
+	}
+
+	output, err := slim.File(logsPath + "/" + logFileProbeContainer).Contents(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &exitCode, output, nil
+}
+
+func shellQuote(val string) string {
+	return "'" + strings.ReplaceAll(val, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	labelStatus = "org.mintoolkit.slim.status"
+	labelReport = "org.mintoolkit.slim.report"
+
+	statusSlimmed   = "slimmed"
+	statusUnslimmed = "unslimmed"
+)
+
+// slimReport describes the outcome of a slim run.
+// It's attached to the returned container as a label (JSON encoded).
+type slimReport struct {
+	Status   string   `json:"status"`
+	Failure  string   `json:"failure,omitempty"`
+	ExitCode int      `json:"exit_code,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Restored []string `json:"restored,omitempty"`
+	//config of the original image that couldn't be restored (e.g. annotation:<key>)
+	NotRestored []string `json:"not_restored,omitempty"`
+	//exit code of the test container used to probe the target
+	ProbeExitCode *int `json:"probe_exit_code,omitempty"`
+}
+
+// unslimmed labels the original container with the report describing why slimming failed
+func unslimmed(container *Container, err error) *Container {
+	report := slimReport{
+		Status:  statusUnslimmed,
+		Failure: FailureUnknown,
+		Reason:  err.Error(),
+	}
+
+	var serr *SlimError
+	if errors.As(err, &serr) {
+		report.Failure = serr.Kind
+		report.ExitCode = serr.ExitCode
+		report.Reason = serr.Excerpt
+	}
+
+	return container.
+		WithLabel(labelStatus, statusUnslimmed).
+		WithLabel(labelReport, strings.TrimSpace(toString(report, false)))
+}
+
+// withReport labels the slimmed container with the report (when there's something to report)
+func withReport(container *Container, report slimReport) *Container {
+	if len(report.Restored) == 0 && len(report.NotRestored) == 0 && report.ProbeExitCode == nil {
+		return container
+	}
+
+	return container.WithLabel(labelReport, strings.TrimSpace(toString(report, false)))
+}
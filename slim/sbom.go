@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	//pinned, so the SBOMs of the same image are reproducible
+	syftImage = "index.docker.io/anchore/syft:v1.2.0"
+
+	sbomFormatSPDX      = "spdx-json"
+	sbomFormatCycloneDX = "cyclonedx-json"
+	sbomFormatSyft      = "syft-json"
+
+	sbomOriginal        = "original"
+	sbomSlimmed         = "slimmed"
+	sbomRemovedPackages = "removed-packages.json"
+	sbomCatalog         = "catalog.syft.json"
+)
+
+var sbomExtensions = map[string]string{
+	sbomFormatSPDX:      ".spdx.json",
+	sbomFormatCycloneDX: ".cdx.json",
+}
+
+// sbomPackage is the subset of the syft (native format) package fields used to diff the SBOMs
+type sbomPackage struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Type      string `json:"type"`
+	Locations []struct {
+		Path string `json:"path"`
+	} `json:"locations,omitempty"`
+	Metadata struct {
+		Files []struct {
+			Path string `json:"path"`
+		} `json:"files"`
+	} `json:"metadata,omitempty"`
+}
+
+// Generate the SBOM (software bill of materials) for the original and slimmed images,
+// along with the list of packages whose files were entirely removed by slimming
+func (s *Slim) Sbom(
+	ctx context.Context,
+	container *Container,
+	// SBOM format - spdx-json | cyclonedx-json
+	// +optional
+	// +default="spdx-json"
+	format string,
+	// Slimmed container (the container is slimmed with the default options if not provided)
+	// +optional
+	slimmed *Container,
+) (*Directory, error) {
+	ext, ok := sbomExtensions[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SBOM format - %s", format)
+	}
+
+	if slimmed == nil {
+		var err error
+		slimmed, err = s.slimDefault(ctx, container)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	original := syft(container.Rootfs(), format, sbomOriginal+ext)
+	rawPackages, err := original.File(sbomCatalog).Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var catalog struct {
+		Artifacts []sbomPackage `json:"artifacts"`
+	}
+	if err := json.Unmarshal([]byte(rawPackages), &catalog); err != nil {
+		return nil, err
+	}
+
+	files, err := rootfsFiles(ctx, slimmed.Rootfs())
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []sbomPackage{}
+	for _, pkg := range catalog.Artifacts {
+		if packageRemoved(pkg, files) {
+			pkg.Locations = nil
+			pkg.Metadata.Files = nil
+			removed = append(removed, pkg)
+		}
+	}
+
+	return dag.
+		Directory().
+		WithFile(sbomOriginal+ext, original.File(sbomOriginal+ext)).
+		WithFile(sbomSlimmed+ext, syft(slimmed.Rootfs(), format, sbomSlimmed+ext).File(sbomSlimmed+ext)).
+		WithNewFile(sbomRemovedPackages, toString(removed, true)), nil
+}
+
+// syft catalogs the packages in the rootfs, producing the SBOM in the requested format
+// (as the named file) and in the syft native format (used to diff the packages)
+func syft(rootfs *Directory, format, name string) *Directory {
+	return dag.
+		Container().
+		From(syftImage).
+		WithMountedDirectory("/rootfs", rootfs).
+		WithMountedDirectory("/sbom", dag.Directory()).
+		WithExec([]string{
+			"dir:/rootfs",
+			"-o", format + "=/sbom/" + name,
+			"-o", sbomFormatSyft + "=/sbom/" + sbomCatalog,
+		}).
+		Directory("/sbom")
+}
+
+// rootfsFiles lists the (non-directory) paths in the rootfs
+func rootfsFiles(ctx context.Context, rootfs *Directory) (map[string]bool, error) {
+	out, err := dag.
+		Container().
+		From("alpine").
+		WithMountedDirectory("/rootfs", rootfs).
+		WithWorkdir("/rootfs").
+		WithExec([]string{"find", ".", "!", "-type", "d"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimPrefix(line, "."); line != "" {
+			files[line] = true
+		}
+	}
+
+	return files, nil
+}
+
+// packageRemoved checks if none of the package files (or locations, when the files are not known) are left
+func packageRemoved(pkg sbomPackage, files map[string]bool) bool {
+	var paths []string
+	for _, f := range pkg.Metadata.Files {
+		paths = append(paths, f.Path)
+	}
+
+	if len(paths) == 0 {
+		for _, l := range pkg.Locations {
+			paths = append(paths, l.Path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return false
+	}
+
+	for _, p := range paths {
+		if files[p] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	//pinned: the DB schema changes between the grype releases, so a DB only loads with the matching grype version
+	grypeImage    = "index.docker.io/anchore/grype:v0.77.0"
+	grypeDBSchema = "5"
+	grypeDBPath   = "/grype-db"
+)
+
+// The vulnerability findings of the original image and the ones eliminated by slimming
+type VulnReport struct {
+	// Number of findings in the original image
+	Original int
+	// Number of findings in the slimmed image
+	Slimmed int
+	// Findings in the original image that are not in the slimmed image
+	Eliminated []*VulnFinding
+	// Findings still in the slimmed image
+	Remaining []*VulnFinding
+}
+
+// A vulnerability found in a package
+type VulnFinding struct {
+	// Vulnerability ID (e.g. CVE-2023-1234)
+	ID       string
+	Severity string
+	Package  string
+	Version  string
+	// Package type (e.g. apk, deb, go-module)
+	Type string
+}
+
+func (f *VulnFinding) key() string {
+	return f.ID + "|" + f.Type + "|" + f.Package + "|" + f.Version
+}
+
+// Scan the original and slimmed images against an offline vulnerability database
+// and report the findings eliminated by slimming
+func (s *Slim) VulnDelta(
+	ctx context.Context,
+	container *Container,
+	// Grype vulnerability database directory (used as the grype DB cache dir, no updates are downloaded).
+	// The scans use grype v0.77.0, which expects a schema v5 DB: the directory contains 5/vulnerability.db
+	// and 5/metadata.json (the layout of the grype cache dir after 'grype db update', or a v5 DB archive extracted in 5/)
+	db *Directory,
+	// Slimmed container (the container is slimmed with the default options if not provided)
+	// +optional
+	slimmed *Container,
+) (*VulnReport, error) {
+	entries, err := db.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !contains(entries, grypeDBSchema+"/") && !contains(entries, grypeDBSchema) {
+		return nil, fmt.Errorf("no grype DB for schema v%s in the DB directory (entries: %v)", grypeDBSchema, entries)
+	}
+
+	if slimmed == nil {
+		slimmed, err = s.slimDefault(ctx, container)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	original, err := grype(ctx, container.Rootfs(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, err := grype(ctx, slimmed.Rootfs(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	left := map[string]bool{}
+	for _, f := range remaining {
+		left[f.key()] = true
+	}
+
+	report := &VulnReport{
+		Original:   len(original),
+		Slimmed:    len(remaining),
+		Eliminated: []*VulnFinding{},
+		Remaining:  remaining,
+	}
+
+	for _, f := range original {
+		if !left[f.key()] {
+			report.Eliminated = append(report.Eliminated, f)
+		}
+	}
+
+	return report, nil
+}
+
+// grype scans the rootfs using only the provided vulnerability database
+func grype(ctx context.Context, rootfs *Directory, db *Directory) ([]*VulnFinding, error) {
+	out, err := dag.
+		Container().
+		From(grypeImage).
+		WithMountedDirectory("/rootfs", rootfs).
+		WithMountedDirectory(grypeDBPath, db).
+		WithEnvVariable("GRYPE_DB_CACHE_DIR", grypeDBPath).
+		WithEnvVariable("GRYPE_DB_AUTO_UPDATE", "false").
+		WithEnvVariable("GRYPE_DB_VALIDATE_AGE", "false").
+		WithExec([]string{"dir:/rootfs", "-o", "json"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID       string `json:"id"`
+				Severity string `json:"severity"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+				Type    string `json:"type"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	findings := []*VulnFinding{}
+	for _, m := range results.Matches {
+		f := &VulnFinding{
+			ID:       m.Vulnerability.ID,
+			Severity: m.Vulnerability.Severity,
+			Package:  m.Artifact.Name,
+			Version:  m.Artifact.Version,
+			Type:     m.Artifact.Type,
+		}
+
+		//the same finding can be matched more than once (e.g. by CPE and by package)
+		if seen[f.key()] {
+			continue
+		}
+
+		seen[f.key()] = true
+		findings = append(findings, f)
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].key() < findings[j].key()
+	})
+
+	return findings, nil
+}